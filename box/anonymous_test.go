@@ -0,0 +1,131 @@
+package box
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestAnonymousSharedBoxes(t *testing.T) {
+	priv, pub, ok := GenerateKey()
+	if !ok {
+		t.Fatal("box: failed to generate key")
+	}
+	msg := []byte(testMessages[2])
+	peers := []PublicKey{testGoodPub, testPeerPub}
+
+	box, ok := SealSharedAnonymous(msg, peers)
+	if !ok {
+		t.Fatal("box: failed to seal anonymous shared box")
+	} else if BoxIsSigned(box) {
+		t.Fatal("box: anonymous shared box should not be signed")
+	}
+
+	for _, peer := range peers {
+		cpeer, _ := CompressKey(peer)
+		if bytes.Contains(box, peer) || bytes.Contains(box, cpeer[1:]) {
+			t.Fatal("box: anonymous shared box contains a recipient's key")
+		}
+	}
+
+	for _, key := range []struct {
+		priv PrivateKey
+		pub  PublicKey
+	}{{testGoodKey, testGoodPub}, {testPeerKey, testPeerPub}} {
+		out, ok := OpenShared(box, key.priv, key.pub)
+		if !ok || !bytes.Equal(out, msg) {
+			t.Fatal("box: failed to open anonymous shared box")
+		}
+	}
+
+	if _, err := OpenSharedE(box, priv, pub); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("box: non-recipient should get ErrNotRecipient, got %v", err)
+	}
+
+	box, ok = SignAndSealSharedAnonymous(msg, peers, priv, pub)
+	if !ok {
+		t.Fatal("box: failed to seal signed anonymous shared box")
+	} else if !BoxIsSigned(box) {
+		t.Fatal("box: signed anonymous shared box should be signed")
+	} else if out, ok := OpenSharedAndVerify(box, testPeerKey, testPeerPub, pub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open signed anonymous shared box")
+	} else if _, err := OpenSharedE(box, testPeerKey, testPeerPub); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("box: OpenShared should not open a signed box, got %v", err)
+	}
+
+	s := NewSealer(&Options{CompressKeys: true})
+	box, ok = s.SealSharedAnonymous(msg, peers)
+	if !ok {
+		t.Fatal("box: failed to seal compressed anonymous shared box")
+	} else if out, ok := OpenShared(box, testGoodKey, testGoodPub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open compressed anonymous shared box")
+	}
+}
+
+func TestAnonymousRelabelling(t *testing.T) {
+	msg := []byte(testMessages[2])
+	peers := []PublicKey{testGoodPub, testPeerPub}
+
+	// The type of a named shared box is not authenticated, as its
+	// format predates anonymous boxes, so a shared box relabelled as
+	// an anonymous one must not open. Neither must an unsigned box
+	// relabelled as a signed one.
+	for _, relabel := range []struct{ from, to byte }{
+		{BoxShared, BoxSharedAnonymous},
+		{BoxShared, BoxSharedSigned},
+		{BoxSharedSigned, BoxSharedAnonymous},
+		{BoxSharedSigned, BoxSharedAnonymousSigned},
+	} {
+		var box []byte
+		var ok bool
+		if relabel.from == BoxShared {
+			box, ok = SealShared(msg, peers)
+		} else {
+			box, ok = SignAndSealShared(msg, peers, testGoodKey, testGoodPub)
+		}
+		if !ok {
+			t.Fatal("box: failed to seal shared box")
+		}
+
+		box[0] = relabel.to
+		if _, ok = OpenShared(box, testGoodKey, testGoodPub); ok {
+			t.Fatalf("box: opened a shared box relabelled from %d to %d", relabel.from, relabel.to)
+		} else if _, ok = OpenSharedAndVerify(box, testGoodKey, testGoodPub, testGoodPub); ok {
+			t.Fatalf("box: opened a shared box relabelled from %d to %d", relabel.from, relabel.to)
+		}
+	}
+}
+
+func TestAnonymousPeerCount(t *testing.T) {
+	msg := []byte(testMessages[2])
+	peers := []PublicKey{testGoodPub, testPeerPub}
+
+	// The peer count follows the type byte, the ephemeral key, the
+	// peer list's length and tag, and the count's own length.
+	off := 1 + 4 + PublicKeySize + 4 + 1 + 4
+	for _, btype := range []byte{BoxSharedAnonymous, BoxSharedAnonymousSigned} {
+		box, ok := SignAndSealShared(msg, peers, testGoodKey, testGoodPub)
+		if !ok {
+			t.Fatal("box: failed to seal shared box")
+		}
+
+		// Relabelling the box and doubling its peer count makes the
+		// named peer list parse as an anonymous one.
+		box[0] = btype
+		count := binary.BigEndian.Uint32(box[off:])
+		binary.BigEndian.PutUint32(box[off:], 2*count)
+		if _, err := OpenSharedE(box, testGoodKey, testGoodPub); err == nil {
+			t.Fatalf("box: opened a relabelled box as type %d", btype)
+		}
+	}
+
+	box, ok := SealSharedAnonymous(msg, peers)
+	if !ok {
+		t.Fatal("box: failed to seal anonymous shared box")
+	}
+	binary.BigEndian.PutUint32(box[off:], 1)
+	if _, ok = OpenShared(box, testGoodKey, testGoodPub); ok {
+		t.Fatal("box: opened an anonymous box with a modified peer count")
+	}
+}
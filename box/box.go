@@ -23,12 +23,17 @@ package box
 
 import (
 	"bytes"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"github.com/kisom/aescrypt/gcmbox"
+	"github.com/kisom/aescrypt/health"
+	"github.com/kisom/aescrypt/internal/selftest"
 	"github.com/kisom/aescrypt/secretbox"
-	"math/big"
+	"io"
+	"sort"
 )
 
 type PublicKey []byte
@@ -39,15 +44,22 @@ const VersionString = "2.0.0"
 const (
 	publicKeySize  = 65
 	privateKeySize = 32
+	maxKeyAttempts = 64
 	sigSize        = 64
 )
 
 const (
 	BoxUnsigned     byte = 1
 	BoxSigned       byte = 2
+	BoxUnsignedGCM  byte = 3
+	BoxFrom         byte = 4
 	BoxShared       byte = 11
 	BoxSharedSigned byte = 12
 	peerList             = 21
+
+	BoxSharedAnonymous       byte = 13
+	BoxSharedAnonymousSigned byte = 14
+	BoxThreshold             byte = 15
 )
 
 const (
@@ -59,30 +71,53 @@ const (
 // for locked and shared boxes.
 var Overhead = publicKeySize + secretbox.Overhead + 9 // 9: two four byte lengths and type
 
+// GCMOverhead is the number of bytes of overhead when boxing a message
+// with SealGCM.
+var GCMOverhead = publicKeySize + gcmbox.Overhead + 9
+
 // SignedOverhead is the number of bytes of overhead when signing and
 // boxing a message.
 var SignedOverhead = publicKeySize + secretbox.Overhead + sigSize
 
-// The default source for random data is the crypto/rand package's Reader.
-var PRNG = rand.Reader
+// The default source for random data is the crypto/rand package's
+// Reader, wrapped in a health.Reader so that a failing source is
+// detected rather than causing keys and IVs to be reused.
+var PRNG io.Reader = health.NewReader(rand.Reader)
+
+// curve only names the curve to crypto/ecdsa, and to decompressKey;
+// all other key handling goes through ecdhCurve.
+var (
+	curve     = elliptic.P256()
+	ecdhCurve = ecdh.P256()
+)
+
+// keyExchange performs the ECDH key agreement method to generate a
+// shared key between a pair of keys.
+func keyExchange(key PrivateKey, peer PublicKey) ([]byte, bool) {
+	if selftest.Failed() {
+		return nil, false
+	}
 
-var curve = elliptic.P256()
+	peer, ok := decodeKey(peer)
+	if !ok {
+		return nil, false
+	}
 
-// ecdh performs the ECDH key agreement method to generate a shared key
-// between a pair of keys.
-func ecdh(key PrivateKey, peer PublicKey) ([]byte, bool) {
-	x, y := elliptic.Unmarshal(curve, peer)
-	if x == nil {
+	priv, err := ecdhCurve.NewPrivateKey(key)
+	if err != nil {
 		return nil, false
-	} else if !curve.IsOnCurve(x, y) {
+	}
+	pub, err := ecdhCurve.NewPublicKey(peer)
+	if err != nil {
 		return nil, false
 	}
 
-	x, _ = curve.ScalarMult(x, y, key)
-	if x == nil {
+	x, err := priv.ECDH(pub)
+	if err != nil {
 		return nil, false
 	}
-	xb := sha256.Sum256(zeroPad(x.Bytes(), SharedKeySize))
+	defer zero(x)
+	xb := sha256.Sum256(zeroPad(x, SharedKeySize))
 
 	skey := xb[:16]
 	mkey := xb[16:]
@@ -95,53 +130,89 @@ func ecdh(key PrivateKey, peer PublicKey) ([]byte, bool) {
 
 // SharedKey precomputes a key for encrypting with secretbox.
 func SharedKey(key PrivateKey, peer PublicKey) (secretbox.Key, bool) {
-	return ecdh(key, peer)
+	return keyExchange(key, peer)
 }
 
 // GenerateKey generates an appropriate private and public keypair for
 // use in box.
 func GenerateKey() (PrivateKey, PublicKey, bool) {
-	key, x, y, err := elliptic.GenerateKey(curve, PRNG)
-	if err != nil {
-		return nil, nil, false
-	}
-	peer := elliptic.Marshal(curve, x, y)
-	if peer == nil {
-	}
-	if len(key) != privateKeySize || len(peer) != publicKeySize {
-		return nil, nil, false
+	return defaultSealer.GenerateKey()
+}
+
+// generateKey generates a private key by rejection sampling: random
+// scalars are drawn from the Sealer's source of random data until one
+// lies in the range [1, N-1]. crypto/ecdh is used to validate the
+// scalar and compute the public key, so that neither is handled as a
+// big.Int.
+func (s *Sealer) generateKey() (PrivateKey, PublicKey, bool) {
+	key := make([]byte, privateKeySize)
+	for i := 0; i < maxKeyAttempts; i++ {
+		if _, err := io.ReadFull(s.reader(), key); err != nil {
+			return nil, nil, false
+		}
+
+		priv, err := ecdhCurve.NewPrivateKey(key)
+		if err != nil {
+			continue
+		}
+
+		peer, ok := encodeKey(priv.PublicKey().Bytes(), s.compress)
+		if !ok {
+			return nil, nil, false
+		}
+		return key, peer, true
 	}
-	return key, peer, true
+	return nil, nil, false
 }
 
-func sealBox(message []byte, peer PublicKey, boxtype byte) *bw {
-	if message == nil {
-		return nil
+func (s *Sealer) sealBox(message []byte, peer PublicKey, boxtype byte) ([]byte, error) {
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	} else if message == nil {
+		return nil, ErrMalformed
 	} else if !KeyIsSuitable(nil, peer) {
-		return nil
+		return nil, ErrInvalidKey
 	}
 
-	eph_key, eph_peer, ok := GenerateKey()
+	eph_key, eph_peer, ok := s.generateKey()
 	if !ok {
-		return nil
+		return nil, ErrRandomness
 	}
 	defer zero(eph_key)
 
-	skey, ok := ecdh(eph_key, peer)
+	skey, ok := keyExchange(eph_key, peer)
 	if !ok {
-		return nil
+		return nil, ErrInvalidKey
 	}
 	defer zero(skey)
 
-	packer := newbw([]byte{boxtype})
-	sbox, ok := secretbox.Seal(message, skey)
+	packer := newbw(s.header(boxtype))
+	var sbox []byte
+	if boxtype == BoxUnsignedGCM {
+		var gkey gcmbox.Key
+		if gkey, ok = gcmKey(skey); !ok {
+			return nil, ErrInvalidKey
+		}
+		defer zero(gkey)
+		var gs *gcmbox.Sealer
+		if gs, ok = gcmbox.NewSealer(gkey, &gcmbox.Options{Rand: s.rand}); !ok {
+			return nil, ErrInvalidKey
+		}
+		sbox, ok = gs.Seal(message)
+	} else {
+		sbox, ok = s.secret.Seal(message, skey)
+	}
 	if !ok {
-		return nil
+		return nil, ErrRandomness
 	}
 
 	packer.Write(eph_peer)
 	packer.Write(sbox)
-	return packer
+	box := packer.Bytes()
+	if box == nil {
+		return nil, ErrMalformed
+	}
+	return box, nil
 }
 
 // Seal returns an authenticated and encrypted message, and a boolean
@@ -151,42 +222,58 @@ func sealBox(message []byte, peer PublicKey, boxtype byte) *bw {
 // a private key. However, if a private key is passed in sigkey (with the
 // corresponding public key in sigpub), the box will be signed.
 func Seal(message []byte, peer PublicKey) (box []byte, ok bool) {
-	packer := sealBox(message, peer, BoxUnsigned)
-	if packer == nil {
-		ok = false
-	} else {
-		box = packer.Bytes()
-		if box == nil {
-			ok = false
-		} else {
-			ok = true
-		}
-	}
-	return
+	box, err := SealE(message, peer)
+	return box, err == nil
 }
 
-func openBox(box []byte, key PrivateKey) (btype byte, message []byte, ok bool) {
-	if box == nil {
-		return 0, nil, false
+// SealE seals a message as Seal does, returning an error describing
+// why sealing failed rather than a boolean.
+func SealE(message []byte, peer PublicKey) (box []byte, err error) {
+	return defaultSealer.SealE(message, peer)
+}
+
+func openBox(box []byte, key PrivateKey) (btype byte, message []byte, err error) {
+	if selftest.Failed() {
+		return 0, nil, ErrSelfTest
+	} else if len(box) == 0 {
+		return 0, nil, ErrMalformed
 	} else if !KeyIsSuitable(key, nil) {
-		return 0, nil, false
+		return 0, nil, ErrInvalidKey
+	}
+	btype, body, compressed, ok := boxHeader(box)
+	if !ok {
+		return 0, nil, ErrMalformed
 	}
-	btype = box[0]
-	unpacker := newbr(box[1:])
+	unpacker := newbr(body)
 	eph_pub := unpacker.Next()
 	sbox := unpacker.Next()
+	if eph_pub == nil || sbox == nil {
+		return 0, nil, ErrMalformed
+	} else if len(eph_pub) != keySizeFor(compressed) {
+		return 0, nil, ErrMalformed
+	}
 
-	shared, ok := ecdh(key, eph_pub)
+	shared, ok := keyExchange(key, eph_pub)
 	if !ok {
-		return 0, nil, false
+		return 0, nil, ErrMalformed
 	}
+	defer zero(shared)
 
-	message, ok = secretbox.Open(sbox, shared)
+	if btype == BoxUnsignedGCM {
+		var gkey gcmbox.Key
+		if gkey, ok = gcmKey(shared); !ok {
+			return 0, nil, ErrMalformed
+		}
+		defer zero(gkey)
+		message, ok = gcmbox.Open(sbox, gkey)
+	} else {
+		message, ok = secretbox.Open(sbox, shared)
+	}
 	if !ok {
-		return 0, nil, false
+		return 0, nil, ErrAuthFailed
 	}
 
-	return btype, message, true
+	return btype, message, nil
 }
 
 // Open authenticates and decrypts a sealed message, also returning
@@ -194,41 +281,74 @@ func openBox(box []byte, key PrivateKey) (btype byte, message []byte, ok bool) {
 // message must be discarded. The returned message will be Overhead
 // bytes shorter than the box.
 func Open(box []byte, key PrivateKey) (message []byte, ok bool) {
-	btype, message, ok := openBox(box, key)
+	message, err := OpenE(box, key)
+	return message, err == nil
+}
+
+// OpenE opens a box as Open does, returning an error describing why
+// opening failed rather than a boolean. A box that was sealed for a
+// different key and a box that has been modified both return
+// ErrAuthFailed.
+func OpenE(box []byte, key PrivateKey) (message []byte, err error) {
+	btype, message, err := openBox(box, key)
+	if err != nil {
+		return nil, err
+	} else if btype != BoxUnsigned && btype != BoxUnsignedGCM {
+		zero(message)
+		return nil, ErrWrongType
+	}
+	return message, nil
+}
+
+// SealGCM seals a message as Seal does, but secures the message with
+// gcmbox rather than secretbox. The box will be GCMOverhead bytes longer
+// than the message, and may be opened with Open.
+func SealGCM(message []byte, peer PublicKey) (box []byte, ok bool) {
+	return defaultSealer.SealGCM(message, peer)
+}
+
+// gcmLabel is used to derive the key for boxes sealed with gcmbox, so
+// that it is independent of the secretbox key used for other boxes.
+var gcmLabel = []byte("box gcm")
+
+func gcmKey(shared secretbox.Key) (gcmbox.Key, bool) {
+	key, ok := secretbox.DeriveKey(shared, nil, gcmLabel)
 	if !ok {
 		return nil, false
-	} else if message == nil {
-		return nil, false
-	} else if btype != BoxUnsigned {
-		return nil, false
 	}
-	return message, true
+	zero(key[gcmbox.KeySize:])
+	return gcmbox.Key(key[:gcmbox.KeySize]), true
 }
 
+// ecdsa_private converts a key pair to an ECDSA signing key. The
+// public key must match the private key.
 func ecdsa_private(key PrivateKey, pub PublicKey) (skey *ecdsa.PrivateKey, ok bool) {
-	x, y := elliptic.Unmarshal(curve, pub)
-	if x == nil {
-		return
+	pub, ok = decodeKey(pub)
+	if !ok {
+		return nil, false
+	}
+
+	skey, err := ecdsa.ParseRawPrivateKey(curve, key)
+	if err != nil {
+		return nil, false
 	}
 
-	skey = new(ecdsa.PrivateKey)
-	skey.D = new(big.Int).SetBytes(key)
-	skey.PublicKey.Curve = curve
-	skey.X = x
-	skey.Y = y
-	ok = true
-	return
+	derived, err := skey.PublicKey.Bytes()
+	if err != nil || !bytes.Equal(derived, pub) {
+		return nil, false
+	}
+	return skey, true
 }
 
 func ecdsa_public(peer PublicKey) (pkey *ecdsa.PublicKey, ok bool) {
-	x, y := elliptic.Unmarshal(curve, peer)
-	if x == nil {
-		return
+	peer, ok = decodeKey(peer)
+	if !ok {
+		return nil, false
 	}
-	pkey = &ecdsa.PublicKey{
-		Curve: curve,
-		X:     x,
-		Y:     y,
+
+	pkey, err := ecdsa.ParseUncompressedPublicKey(curve, peer)
+	if err != nil {
+		return nil, false
 	}
 	return pkey, true
 }
@@ -237,10 +357,23 @@ func ecdsa_public(peer PublicKey) (pkey *ecdsa.PublicKey, ok bool) {
 // boolean indicating success; on success, the signature value returned will
 // contain the signature.
 func Sign(message []byte, key PrivateKey, pub PublicKey) (signature []byte, ok bool) {
-	if message == nil {
-		return nil, false
+	signature, err := SignE(message, key, pub)
+	return signature, err == nil
+}
+
+// SignE signs a message as Sign does, returning an error describing
+// why signing failed rather than a boolean.
+func SignE(message []byte, key PrivateKey, pub PublicKey) (signature []byte, err error) {
+	return defaultSealer.SignE(message, key, pub)
+}
+
+func (s *Sealer) sign(message []byte, key PrivateKey, pub PublicKey) (signature []byte, err error) {
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	} else if message == nil {
+		return nil, ErrMalformed
 	} else if !KeyIsSuitable(key, pub) {
-		return nil, false
+		return nil, ErrInvalidKey
 	}
 	h := sha256.New()
 	h.Write(message)
@@ -248,101 +381,136 @@ func Sign(message []byte, key PrivateKey, pub PublicKey) (signature []byte, ok b
 
 	skey, ok := ecdsa_private(key, pub)
 	if !ok {
-		return
+		return nil, ErrInvalidKey
 	}
-	r, s, err := ecdsa.Sign(PRNG, skey, hash)
+	r, ss, err := ecdsa.Sign(s.reader(), skey, hash)
 	if err != nil {
-		ok = false
-	} else {
-		signature = marshalSignature(r, s)
-		if signature == nil {
-			ok = false
-		}
+		return nil, ErrRandomness
+	}
+	signature = marshalSignature(r, ss)
+	if signature == nil {
+		return nil, ErrMalformed
 	}
-	return
+	return signature, nil
 }
 
 // Verify returns true if the signature is a valid signature by the signer
 // for the message. If there is a failure (include failing to verify the
 // signature), Verify returns false.
 func Verify(message, signature []byte, signer PublicKey) bool {
-	if message == nil || signature == nil {
-		return false
+	return VerifyE(message, signature, signer) == nil
+}
+
+// VerifyE checks a signature as Verify does, returning nil if the
+// signature is valid and an error describing the failure otherwise.
+func VerifyE(message, signature []byte, signer PublicKey) error {
+	if selftest.Failed() {
+		return ErrSelfTest
+	} else if message == nil || signature == nil {
+		return ErrMalformed
 	} else if !KeyIsSuitable(nil, signer) {
-		return false
+		return ErrInvalidKey
 	}
 	r, s := unmarshalSignature(signature)
 	if r == nil || s == nil {
-		return false
+		return ErrBadSignature
 	}
 	h := sha256.New()
 	h.Write(message)
 
 	pub, ok := ecdsa_public(signer)
 	if !ok {
-		return false
+		return ErrInvalidKey
+	}
+	if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+		return ErrBadSignature
 	}
-	return ecdsa.Verify(pub, h.Sum(nil), r, s)
+	return nil
 }
 
-// SignAndSeal adds a digital signature to the message before sealing it.
-func SignAndSeal(message []byte, key PrivateKey, public PublicKey, peer PublicKey) (box []byte, ok bool) {
-	sig, ok := Sign(message, key, public)
-	if !ok || sig == nil {
-		return nil, false
+// signMessage signs a message and packs it with its signature, ready
+// to be sealed in a signed box.
+func (s *Sealer) signMessage(message []byte, key PrivateKey, public PublicKey) ([]byte, error) {
+	sig, err := s.sign(message, key, public)
+	if err != nil {
+		return nil, err
 	}
 	mpack := newbw(nil)
 	mpack.Write(message)
 	mpack.Write(sig)
 	signedMessage := mpack.Bytes()
 	if signedMessage == nil {
-		return nil, false
-	}
-	defer zero(signedMessage)
-	packer := sealBox(signedMessage, peer, BoxSigned)
-	if packer == nil {
-		return nil, false
-	}
-	box = packer.Bytes()
-	if box == nil {
-		return nil, false
+		return nil, ErrMalformed
 	}
-	return box, true
+	return signedMessage, nil
 }
 
-// OpenAndVerify opens a signed box, and verifies the signature. If the box
-// couldn't be opened or the signature is invalid, OpenAndVerify returns false,
-// and the message value must be discarded.
-func OpenAndVerify(box []byte, key PrivateKey, peer PublicKey) (message []byte, ok bool) {
-	btype, smessage, ok := openBox(box, key)
-	if !ok || smessage == nil {
-		return nil, false
-	} else if btype != BoxSigned {
-		return nil, false
-	}
+// verifyMessage unpacks a signed message and verifies its signature.
+func verifyMessage(smessage []byte, signer PublicKey) ([]byte, error) {
 	mpack := newbr(smessage)
-	message = mpack.Next()
+	message := mpack.Next()
 	if message == nil {
-		return nil, false
+		return nil, ErrMalformed
 	}
 	sig := mpack.Next()
 	if sig == nil {
-		return nil, false
+		return nil, ErrMalformed
 	}
 
-	if !Verify(message, sig, peer) {
-		return nil, false
+	if err := VerifyE(message, sig, signer); err != nil {
+		return nil, err
 	}
-	return message, true
+	return message, nil
+}
+
+// SignAndSeal adds a digital signature to the message before sealing it.
+func SignAndSeal(message []byte, key PrivateKey, public PublicKey, peer PublicKey) (box []byte, ok bool) {
+	box, err := SignAndSealE(message, key, public, peer)
+	return box, err == nil
+}
+
+// SignAndSealE signs and seals a message as SignAndSeal does,
+// returning an error describing why sealing failed rather than a
+// boolean.
+func SignAndSealE(message []byte, key PrivateKey, public PublicKey, peer PublicKey) (box []byte, err error) {
+	return defaultSealer.SignAndSealE(message, key, public, peer)
+}
+
+// OpenAndVerify opens a signed box, and verifies the signature. If the box
+// couldn't be opened or the signature is invalid, OpenAndVerify returns false,
+// and the message value must be discarded.
+func OpenAndVerify(box []byte, key PrivateKey, peer PublicKey) (message []byte, ok bool) {
+	message, err := OpenAndVerifyE(box, key, peer)
+	return message, err == nil
+}
+
+// OpenAndVerifyE opens a signed box as OpenAndVerify does, returning
+// an error describing why opening failed rather than a boolean. The
+// signature is only checked once the box has been authenticated;
+// ErrBadSignature means that the box is intact, but was not signed
+// by peer.
+func OpenAndVerifyE(box []byte, key PrivateKey, peer PublicKey) (message []byte, err error) {
+	btype, smessage, err := openBox(box, key)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(smessage)
+	if btype != BoxSigned {
+		return nil, ErrWrongType
+	}
+	return verifyMessage(smessage, peer)
 }
 
 // BoxIsSigned returns true if the box is a signed box, and false otherwise.
 func BoxIsSigned(box []byte) bool {
-	if box == nil {
+	btype, _, _, ok := boxHeader(box)
+	if !ok {
 		return false
-	} else if box[0] == BoxSigned {
+	} else if btype == BoxSigned {
 		return true
-	} else if box[0] == BoxSharedSigned {
+	} else if btype == BoxSharedSigned {
+		return true
+	} else if btype == BoxSharedAnonymousSigned {
 		return true
 	} else {
 		return false
@@ -351,13 +519,13 @@ func BoxIsSigned(box []byte) bool {
 
 // IsKeySuitable takes a private and/or public key, and returns true if
 // all keys passed in are valid. If no key is passed in, or any key passed
-// in is invalid, it will return false.
+// in is invalid, it will return false. Public keys may be compressed.
 func KeyIsSuitable(key PrivateKey, pub PublicKey) bool {
 	if key == nil && pub == nil {
 		return false
 	} else if key != nil && len(key) != privateKeySize {
 		return false
-	} else if pub != nil && len(pub) != publicKeySize {
+	} else if pub != nil && len(pub) != publicKeySize && len(pub) != compressedKeySize {
 		return false
 	}
 	return true
@@ -367,19 +535,34 @@ func KeyIsSuitable(key PrivateKey, pub PublicKey) bool {
 // sign the peer key. It returns a signature and true on success;
 // if ok is false, the signature should be discarded as signing failed.
 func SignKey(priv PrivateKey, pub, peer PublicKey) (sig []byte, ok bool) {
+	return defaultSealer.SignKey(priv, pub, peer)
+}
+
+func (s *Sealer) signKey(priv PrivateKey, pub, peer PublicKey) (sig []byte, ok bool) {
+	if selftest.Failed() {
+		return nil, false
+	}
+
 	key, ok := ecdsa_private(priv, pub)
 	if !ok {
 		return nil, false
 	}
 
+	// The signature covers the uncompressed key, so that it is valid
+	// for either encoding.
+	peer, ok = decodeKey(peer)
+	if !ok {
+		return nil, false
+	}
+
 	h := sha256.New()
 	h.Write(peer)
 	m := h.Sum(nil)
-	r, s, err := ecdsa.Sign(PRNG, key, m)
+	r, ss, err := ecdsa.Sign(s.reader(), key, m)
 	if err != nil {
 		return nil, false
 	}
-	sig = marshalSignature(r, s)
+	sig = marshalSignature(r, ss)
 	if sig == nil {
 		return nil, false
 	}
@@ -390,6 +573,10 @@ func SignKey(priv PrivateKey, pub, peer PublicKey) (sig []byte, ok bool) {
 // key. It returns true if the signature is valid, or false if the
 // signature is invalid or an error occurred.
 func VerifySignedKey(pub, sigpub PublicKey, sig []byte) bool {
+	if selftest.Failed() {
+		return false
+	}
+
 	ecpub, ok := ecdsa_public(sigpub)
 	if !ok {
 		return false
@@ -400,70 +587,108 @@ func VerifySignedKey(pub, sigpub PublicKey, sig []byte) bool {
 		return false
 	}
 
+	pub, ok = decodeKey(pub)
+	if !ok {
+		return false
+	}
+
 	h := sha256.New()
 	h.Write(pub)
 	m := h.Sum(nil)
 	return ecdsa.Verify(ecpub, m, r, s)
 }
 
-func boxForPeer(e_priv PrivateKey, peer PublicKey, key secretbox.Key) ([]byte, bool) {
-	shared, ok := ecdh(e_priv, peer)
+func (s *Sealer) boxForPeer(e_priv PrivateKey, peer PublicKey, key secretbox.Key) ([]byte, bool) {
+	shared, ok := keyExchange(e_priv, peer)
 	if !ok {
 		return nil, false
 	}
 	defer zero(shared)
-	return secretbox.Seal(key, shared)
+	return s.secret.Seal(key, shared)
 
 }
 
-func buildSharedBox(message []byte, peers []PublicKey, btype byte) []byte {
-	if message == nil {
-		return nil
+func (s *Sealer) buildSharedBox(message []byte, peers []PublicKey, btype byte) ([]byte, error) {
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	} else if message == nil {
+		return nil, ErrMalformed
 	}
 
 	for _, peer := range peers {
 		if peer == nil {
-			return nil
+			return nil, ErrInvalidKey
 		} else if !KeyIsSuitable(nil, peer) {
-			return nil
+			return nil, ErrInvalidKey
 		}
 	}
 
-	e_priv, e_pub, ok := GenerateKey()
+	e_priv, e_pub, ok := s.generateKey()
 	if !ok {
-		return nil
+		return nil, ErrRandomness
 	}
+	defer zero(e_priv)
 
-	shared, ok := secretbox.GenerateKey()
+	shared, ok := s.secret.GenerateKey()
 	if !ok {
-		return nil
+		return nil, ErrRandomness
 	}
 	defer zero(shared)
 
 	packPeers := newbw([]byte{peerList})
 	packPeers.WriteUint32(uint32(len(peers)))
-	for _, peer := range peers {
-		packPeers.Write(peer)
-		pbox, ok := boxForPeer(e_priv, peer, shared)
-		if !ok {
-			return nil
+	if isAnonymous(btype) {
+		// Anonymous boxes list only each peer's box. These are
+		// sorted, and so ordered by their random IVs, so that the
+		// order of the entries does not reveal the order in which
+		// the peers were given.
+		pboxes := make([][]byte, 0, len(peers))
+		for _, peer := range peers {
+			pbox, ok := s.boxForPeer(e_priv, peer, shared)
+			if !ok {
+				return nil, ErrInvalidKey
+			}
+			pboxes = append(pboxes, pbox)
+		}
+		sort.Slice(pboxes, func(i, j int) bool {
+			return bytes.Compare(pboxes[i], pboxes[j]) < 0
+		})
+		for _, pbox := range pboxes {
+			packPeers.Write(pbox)
+		}
+	} else {
+		for _, peer := range peers {
+			entry, ok := encodeKey(peer, s.compress)
+			if !ok {
+				return nil, ErrInvalidKey
+			}
+			packPeers.Write(entry)
+			pbox, ok := s.boxForPeer(e_priv, peer, shared)
+			if !ok {
+				return nil, ErrInvalidKey
+			}
+			packPeers.Write(pbox)
 		}
-		packPeers.Write(pbox)
 	}
 	plist := packPeers.Bytes()
 	if plist == nil {
-		return nil
+		return nil, ErrMalformed
 	}
 
-	packer := newbw([]byte{btype})
+	hdr := s.header(btype)
+	packer := newbw(hdr)
 	packer.Write(e_pub)
 	packer.Write(plist)
-	sbox, ok := secretbox.Seal(message, shared)
+	sbox, ok := s.secret.SealWithAD(message, sharedAD(hdr, btype, e_pub, plist), shared)
 	if !ok {
-		return nil
+		return nil, ErrRandomness
 	}
 	packer.Write(sbox)
-	return packer.Bytes()
+	box := packer.Bytes()
+	if box == nil {
+		return nil, ErrMalformed
+	}
+	return box, nil
 }
 
 // SealShared returns an authenticated and encrypted message shared
@@ -472,114 +697,224 @@ func buildSharedBox(message []byte, peers []PublicKey, btype byte) []byte {
 // successfully sealed. These boxes are not dependent on having a private
 // key.
 func SealShared(message []byte, peers []PublicKey) (box []byte, ok bool) {
-	box = buildSharedBox(message, peers, BoxShared)
-	if box == nil {
-		ok = false
-	} else {
-		ok = true
-	}
-	return box, ok
+	box, err := SealSharedE(message, peers)
+	return box, err == nil
+}
+
+// SealSharedE seals a shared box as SealShared does, returning an
+// error describing why sealing failed rather than a boolean.
+func SealSharedE(message []byte, peers []PublicKey) (box []byte, err error) {
+	return defaultSealer.SealSharedE(message, peers)
 }
 
 // SignAndSealShared adds a digital signature to the shared message before
 // sealing it.
 func SignAndSealShared(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, ok bool) {
-	sig, ok := Sign(message, sigkey, sigpub)
+	box, err := SignAndSealSharedE(message, peers, sigkey, sigpub)
+	return box, err == nil
+}
+
+// SignAndSealSharedE signs and seals a shared box as
+// SignAndSealShared does, returning an error describing why sealing
+// failed rather than a boolean.
+func SignAndSealSharedE(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, err error) {
+	return defaultSealer.SignAndSealSharedE(message, peers, sigkey, sigpub)
+}
+
+// isAnonymous returns true if btype is an anonymous shared box.
+func isAnonymous(btype byte) bool {
+	return btype == BoxSharedAnonymous || btype == BoxSharedAnonymousSigned
+}
+
+// sharedAD returns the associated data for an anonymous shared box's
+// message, which binds the message to the box's header, ephemeral key
+// and peer list: the box type is not otherwise authenticated, and a
+// box relabelled as another type could be opened as the wrong kind of
+// box. Named shared boxes keep the format they have always had, with
+// no associated data, so sharedAD returns nil for them.
+func sharedAD(hdr []byte, btype byte, e_pub, plist []byte) []byte {
+	if !isAnonymous(btype) {
+		return nil
+	}
+
+	ad := newbw(append([]byte{}, hdr...))
+	ad.Write(e_pub)
+	ad.Write(plist)
+	return ad.Bytes()
+}
+
+// findAnonymousEntry searches the peer list of an anonymous shared box
+// for an entry that opens with the shared key between key and the
+// box's ephemeral key, and returns the box's key. The box type is not
+// authenticated, so the peer list must hold exactly peerCount entries:
+// otherwise, a shared box whose type had been changed to an anonymous
+// one could be opened as the wrong type of box.
+func findAnonymousEntry(peerUnpack *br, peerCount uint32, key PrivateKey, e_pub PublicKey) ([]byte, error) {
+	skey, ok := keyExchange(key, e_pub)
 	if !ok {
-		return nil, false
+		return nil, ErrMalformed
 	}
-	mpack := newbw(nil)
-	mpack.Write(message)
-	mpack.Write(sig)
-	signedMessage := mpack.Bytes()
-	if signedMessage == nil {
-		return nil, false
+	defer zero(skey)
+
+	var shared []byte
+	for i := uint32(0); i < peerCount; i++ {
+		sbox := peerUnpack.Next()
+		if sbox == nil {
+			zero(shared)
+			return nil, ErrMalformed
+		} else if shared == nil {
+			shared, _ = secretbox.Open(sbox, skey)
+		}
 	}
-	defer zero(signedMessage)
 
-	box = buildSharedBox(signedMessage, peers, BoxSharedSigned)
-	if box == nil {
-		ok = false
-	} else {
-		ok = true
+	if peerUnpack.Len() != 0 {
+		zero(shared)
+		return nil, ErrMalformed
+	} else if shared == nil {
+		return nil, ErrNotRecipient
 	}
-	return box, ok
+	return shared, nil
 }
 
-func unpackSharedBox(box []byte, key PrivateKey, public PublicKey) (btype byte, message []byte, ok bool) {
-	if box == nil {
-		return 0, nil, false
+// SealSharedAnonymous seals a shared box as SealShared does, but
+// without listing the peers' public keys: each peer finds its entry
+// by trial decryption when the box is opened. The box still reveals
+// the number of peers. It is opened with OpenShared.
+func SealSharedAnonymous(message []byte, peers []PublicKey) (box []byte, ok bool) {
+	box, err := SealSharedAnonymousE(message, peers)
+	return box, err == nil
+}
+
+// SealSharedAnonymousE seals an anonymous shared box as
+// SealSharedAnonymous does, returning an error describing why sealing
+// failed rather than a boolean.
+func SealSharedAnonymousE(message []byte, peers []PublicKey) (box []byte, err error) {
+	return defaultSealer.SealSharedAnonymousE(message, peers)
+}
+
+// SignAndSealSharedAnonymous adds a digital signature to the message
+// before sealing it in an anonymous shared box. It is opened with
+// OpenSharedAndVerify.
+func SignAndSealSharedAnonymous(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, ok bool) {
+	box, err := SignAndSealSharedAnonymousE(message, peers, sigkey, sigpub)
+	return box, err == nil
+}
+
+// SignAndSealSharedAnonymousE signs and seals an anonymous shared box
+// as SignAndSealSharedAnonymous does, returning an error describing
+// why sealing failed rather than a boolean.
+func SignAndSealSharedAnonymousE(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, err error) {
+	return defaultSealer.SignAndSealSharedAnonymousE(message, peers, sigkey, sigpub)
+}
+
+func unpackSharedBox(box []byte, key PrivateKey, public PublicKey) (btype byte, message []byte, err error) {
+	if selftest.Failed() {
+		return 0, nil, ErrSelfTest
+	} else if len(box) == 0 {
+		return 0, nil, ErrMalformed
 	} else if !KeyIsSuitable(key, public) {
-		return 0, nil, false
+		return 0, nil, ErrInvalidKey
+	}
+	btype, body, compressed, ok := boxHeader(box)
+	if !ok {
+		return 0, nil, ErrMalformed
+	}
+	hdr := box[:len(box)-len(body)]
+
+	// Peers are listed using the box's key encoding.
+	public, ok = encodeKey(public, compressed)
+	if !ok {
+		return 0, nil, ErrInvalidKey
 	}
-	btype = box[0]
 
-	unpacker := newbr(box[1:])
+	unpacker := newbr(body)
 	e_pub := unpacker.Next()
 	if e_pub == nil {
-		return 0, nil, false
+		return 0, nil, ErrMalformed
+	} else if len(e_pub) != keySizeFor(compressed) {
+		return 0, nil, ErrMalformed
 	}
 
 	packedPeers := unpacker.Next()
 	if packedPeers == nil {
-		return 0, nil, false
+		return 0, nil, ErrMalformed
 	} else if packedPeers[0] != peerList {
-		return 0, nil, false
+		return 0, nil, ErrMalformed
 	}
 	peerUnpack := newbr(packedPeers[1:])
 	peerCount, ok := peerUnpack.NextU32()
 	if !ok {
-		return 0, nil, false
+		return 0, nil, ErrMalformed
 	}
 
 	var shared []byte = nil
-	defer zero(shared)
+	defer func() { zero(shared) }()
 
-	for i := uint32(0); i < peerCount; i++ {
-		peer := peerUnpack.Next()
-		if peer == nil {
-			return 0, nil, false
-		}
-		sbox := peerUnpack.Next()
-		if sbox == nil {
-			return 0, nil, false
-		} else if !bytes.Equal(peer, public) {
-			continue
+	if isAnonymous(btype) {
+		shared, err = findAnonymousEntry(peerUnpack, peerCount, key, e_pub)
+		if err != nil {
+			return 0, nil, err
 		}
-		skey, ok := ecdh(key, e_pub)
-		if !ok {
-			return 0, nil, false
-		}
-		shared, ok = secretbox.Open(sbox, skey)
-		if !ok {
-			return 0, nil, false
+	} else {
+		for i := uint32(0); i < peerCount; i++ {
+			peer := peerUnpack.Next()
+			if peer == nil {
+				return 0, nil, ErrMalformed
+			}
+			sbox := peerUnpack.Next()
+			if sbox == nil {
+				return 0, nil, ErrMalformed
+			} else if !bytes.Equal(peer, public) {
+				continue
+			}
+			skey, ok := keyExchange(key, e_pub)
+			if !ok {
+				return 0, nil, ErrMalformed
+			}
+			shared, ok = secretbox.Open(sbox, skey)
+			zero(skey)
+			if !ok {
+				return 0, nil, ErrAuthFailed
+			}
+			break
 		}
-		break
 	}
 	if shared == nil {
-		return 0, nil, false
+		return 0, nil, ErrNotRecipient
 	}
 	sbox := unpacker.Next()
 	if sbox == nil {
-		return 0, nil, false
+		return 0, nil, ErrMalformed
 	}
-	message, ok = secretbox.Open(sbox, shared)
-	return btype, message, ok
+	message, ok = secretbox.OpenWithAD(sbox, sharedAD(hdr, btype, e_pub, packedPeers), shared)
+	if !ok {
+		return 0, nil, ErrAuthFailed
+	}
+	return btype, message, nil
 }
 
 // OpenShared authenticates and decrypts a sealed shared message, also
 // returning whether the message was successfully opened. If this is
 // false, the message must be discarded.
 func OpenShared(box []byte, key PrivateKey, public PublicKey) (message []byte, ok bool) {
-	btype, message, ok := unpackSharedBox(box, key, public)
-	if !ok {
-		return nil, false
-	} else if message == nil {
-		return nil, false
-	} else if btype != BoxShared {
-		return nil, false
+	message, err := OpenSharedE(box, key, public)
+	return message, err == nil
+}
+
+// OpenSharedE opens a shared box as OpenShared does, returning an
+// error describing why opening failed rather than a boolean. If public
+// is not one of the box's recipients, it returns ErrNotRecipient.
+// Anonymous shared boxes are also accepted; for these, the recipient
+// is found by trial decryption with key.
+func OpenSharedE(box []byte, key PrivateKey, public PublicKey) (message []byte, err error) {
+	btype, message, err := unpackSharedBox(box, key, public)
+	if err != nil {
+		return nil, err
+	} else if btype != BoxShared && btype != BoxSharedAnonymous {
+		zero(message)
+		return nil, ErrWrongType
 	}
-	return message, true
+	return message, nil
 }
 
 // OpenSharedAndVerify opens a signed shared box, and verifies the
@@ -587,27 +922,21 @@ func OpenShared(box []byte, key PrivateKey, public PublicKey) (message []byte, o
 // OpenSharedAndVerify returns false, and the message value must be
 // discarded.
 func OpenSharedAndVerify(box []byte, key PrivateKey, public PublicKey, signer PublicKey) (message []byte, ok bool) {
-	btype, smessage, ok := unpackSharedBox(box, key, public)
-	if !ok {
-		return nil, false
-	} else if smessage == nil {
-		return nil, false
-	} else if btype != BoxSharedSigned {
-		return nil, false
-	}
+	message, err := OpenSharedAndVerifyE(box, key, public, signer)
+	return message, err == nil
+}
 
-	mpack := newbr(smessage)
-	message = mpack.Next()
-	if message == nil {
-		return nil, false
-	}
-	sig := mpack.Next()
-	if sig == nil {
-		return nil, false
+// OpenSharedAndVerifyE opens a signed shared box as
+// OpenSharedAndVerify does, returning an error describing why opening
+// failed rather than a boolean.
+func OpenSharedAndVerifyE(box []byte, key PrivateKey, public PublicKey, signer PublicKey) (message []byte, err error) {
+	btype, smessage, err := unpackSharedBox(box, key, public)
+	if err != nil {
+		return nil, err
 	}
-
-	if !Verify(message, sig, signer) {
-		return nil, false
+	defer zero(smessage)
+	if btype != BoxSharedSigned && btype != BoxSharedAnonymousSigned {
+		return nil, ErrWrongType
 	}
-	return message, true
+	return verifyMessage(smessage, signer)
 }
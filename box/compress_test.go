@@ -0,0 +1,152 @@
+package box
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressKey(t *testing.T) {
+	cpub, ok := CompressKey(testGoodPub)
+	if !ok {
+		t.Fatal("box: failed to compress key")
+	} else if len(cpub) != CompressedKeySize {
+		t.Fatalf("box: compressed key should be %d bytes, not %d", CompressedKeySize, len(cpub))
+	} else if !KeyIsSuitable(nil, cpub) {
+		t.Fatal("box: compressed key should be suitable")
+	}
+
+	if again, ok := CompressKey(cpub); !ok || !bytes.Equal(again, cpub) {
+		t.Fatal("box: compressing a compressed key should not change it")
+	}
+
+	pub, ok := DecompressKey(cpub)
+	if !ok {
+		t.Fatal("box: failed to decompress key")
+	} else if !bytes.Equal(pub, testGoodPub) {
+		t.Fatal("box: key did not round trip")
+	}
+
+	bad := append(PublicKey{}, cpub...)
+	bad[0] = 4
+	if _, ok = DecompressKey(bad); ok {
+		t.Fatal("box: decompressed a key with an invalid prefix")
+	}
+
+	// An X coordinate larger than the field's prime is rejected.
+	bad = append(PublicKey{2}, bytes.Repeat([]byte{0xff}, CompressedKeySize-1)...)
+	if _, ok = DecompressKey(bad); ok {
+		t.Fatal("box: decompressed a key with an out of range coordinate")
+	} else if _, ok = CompressKey(testGoodPub[1:]); ok {
+		t.Fatal("box: compressed a truncated key")
+	}
+
+	if k1, ok := SharedKey(testGoodKey, testPeerPub); !ok {
+		t.Fatal("box: failed to compute shared key")
+	} else if cpeer, _ := CompressKey(testPeerPub); cpeer == nil {
+		t.Fatal("box: failed to compress key")
+	} else if k2, ok := SharedKey(testGoodKey, cpeer); !ok || !bytes.Equal(k1, k2) {
+		t.Fatal("box: shared key depends on the key encoding")
+	}
+}
+
+func TestCompressedBoxes(t *testing.T) {
+	s := NewSealer(&Options{CompressKeys: true})
+	priv, pub, ok := s.GenerateKey()
+	if !ok {
+		t.Fatal("box: failed to generate key")
+	} else if len(pub) != CompressedKeySize {
+		t.Fatal("box: sealer should generate compressed keys")
+	}
+	upub, _ := DecompressKey(pub)
+	msg := []byte(testMessages[0])
+
+	box, ok := s.Seal(msg, upub)
+	if !ok {
+		t.Fatal("box: failed to seal message")
+	} else if box[0] != BoxCompressed {
+		t.Fatal("box: compressed box should begin with its version byte")
+	} else if len(box) != len(msg)+CompressedOverhead {
+		t.Fatalf("box: compressed box should be %d bytes, not %d", len(msg)+CompressedOverhead, len(box))
+	} else if out, ok := Open(box, priv); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open compressed box")
+	}
+
+	// Removing the version byte must not allow the box to be opened.
+	if _, ok = Open(box[1:], priv); ok {
+		t.Fatal("box: opened a compressed box without its version byte")
+	}
+
+	// An uncompressed box may be sealed to a compressed key.
+	box, ok = Seal(msg, pub)
+	if !ok {
+		t.Fatal("box: failed to seal message")
+	} else if box[0] != BoxUnsigned || len(box) != len(msg)+Overhead {
+		t.Fatal("box: default sealer should not compress keys")
+	} else if out, ok := Open(box, priv); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open box")
+	}
+
+	box, ok = s.SignAndSeal(msg, priv, pub, testPeerPub)
+	if !ok {
+		t.Fatal("box: failed to seal signed message")
+	} else if !BoxIsSigned(box) {
+		t.Fatal("box: compressed signed box should be signed")
+	} else if out, ok := OpenAndVerify(box, testPeerKey, upub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open compressed signed box")
+	}
+
+	sig, ok := SignKey(priv, pub, testPeerPub)
+	if !ok {
+		t.Fatal("box: failed to sign key")
+	}
+	cpeer, _ := CompressKey(testPeerPub)
+	if !VerifySignedKey(cpeer, upub, sig) {
+		t.Fatal("box: key signature depends on the key encoding")
+	}
+}
+
+func TestCompressedSharedBoxes(t *testing.T) {
+	s := NewSealer(&Options{CompressKeys: true})
+	priv, pub, ok := s.GenerateKey()
+	if !ok {
+		t.Fatal("box: failed to generate key")
+	}
+	upub, _ := DecompressKey(pub)
+	msg := []byte(testMessages[1])
+
+	peers := []PublicKey{testPeerPub, pub}
+	box, ok := s.SignAndSealShared(msg, peers, priv, pub)
+	if !ok {
+		t.Fatal("box: failed to seal shared box")
+	} else if !BoxIsSigned(box) {
+		t.Fatal("box: compressed shared box should be signed")
+	}
+
+	uncompressed, ok := SignAndSealShared(msg, peers, priv, pub)
+	if !ok {
+		t.Fatal("box: failed to seal shared box")
+	}
+
+	// The ephemeral key and each peer's key are compressed, at the
+	// cost of the version byte.
+	cbox, ok := s.SealShared(msg, peers)
+	if !ok {
+		t.Fatal("box: failed to seal shared box")
+	} else if ubox, ok := SealShared(msg, peers); !ok {
+		t.Fatal("box: failed to seal shared box")
+	} else if saved := len(ubox) - len(cbox); saved != 3*(PublicKeySize-CompressedKeySize)-1 {
+		t.Fatalf("box: compressed shared box saved %d bytes", saved)
+	}
+
+	for _, b := range [][]byte{box, uncompressed} {
+		for _, key := range []PublicKey{pub, upub} {
+			out, ok := OpenSharedAndVerify(b, priv, key, pub)
+			if !ok || !bytes.Equal(out, msg) {
+				t.Fatal("box: failed to open shared box")
+			}
+		}
+		if out, ok := OpenSharedAndVerify(b, testPeerKey, testPeerPub, upub); !ok || !bytes.Equal(out, msg) {
+			t.Fatal("box: failed to open shared box")
+		}
+	}
+}
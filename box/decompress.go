@@ -0,0 +1,29 @@
+package box
+
+import "crypto/elliptic"
+
+// decompressKey returns the uncompressed encoding of a compressed
+// public key.
+//
+// This is the only place this package does point arithmetic with
+// crypto/elliptic: neither crypto/ecdh nor crypto/ecdsa can decode a
+// compressed point, so Y is recovered with elliptic.UnmarshalCompressed.
+// Its big.Int arithmetic is not constant time, but it only ever handles
+// public keys, and the point it returns is checked by crypto/ecdh like
+// any other public key. It should go once the standard library can
+// decode compressed points.
+func decompressKey(pub PublicKey) (PublicKey, bool) {
+	x, y := elliptic.UnmarshalCompressed(curve, pub)
+	if x == nil {
+		return nil, false
+	}
+
+	out := make(PublicKey, publicKeySize)
+	out[0] = 4
+	x.FillBytes(out[1 : 1+coordSize])
+	y.FillBytes(out[1+coordSize:])
+	if _, err := ecdhCurve.NewPublicKey(out); err != nil {
+		return nil, false
+	}
+	return out, true
+}
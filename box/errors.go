@@ -0,0 +1,23 @@
+package box
+
+import "errors"
+
+// Errors returned by the error-returning variants of the package's
+// functions. They may be compared with errors.Is. ErrAuthFailed is
+// returned for any box that fails authentication: a box sealed for a
+// different key is deliberately indistinguishable from one that has
+// been modified.
+var (
+	ErrInvalidKey   = errors.New("box: invalid key")
+	ErrMalformed    = errors.New("box: malformed box")
+	ErrAuthFailed   = errors.New("box: message authentication failed")
+	ErrRandomness   = errors.New("box: failed to read random data")
+	ErrWrongType    = errors.New("box: wrong box type")
+	ErrNotRecipient = errors.New("box: not a recipient of the shared box")
+	ErrBadSignature = errors.New("box: invalid signature")
+	ErrSelfTest     = errors.New("box: self-test failed")
+
+	ErrReplay           = errors.New("box: replayed or out-of-order session message")
+	ErrCounterExhausted = errors.New("box: session message counter exhausted")
+	ErrTooFewShares     = errors.New("box: too few shares to open the threshold box")
+)
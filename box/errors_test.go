@@ -0,0 +1,67 @@
+package box
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	priv, pub, ok := GenerateKey()
+	if !ok {
+		t.Fatal("box: failed to generate key")
+	}
+	otherPriv, otherPub, ok := GenerateKey()
+	if !ok {
+		t.Fatal("box: failed to generate key")
+	}
+	msg := []byte(testMessages[0])
+
+	if _, err := SealE(msg, pub[1:]); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("box: expected ErrInvalidKey, got %v", err)
+	}
+
+	box, err := SealE(msg, pub)
+	if err != nil {
+		t.Fatalf("box: failed to seal message: %v", err)
+	} else if _, err = OpenE(box[:1], priv); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("box: expected ErrMalformed, got %v", err)
+	} else if _, err = OpenE(nil, priv); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("box: expected ErrMalformed, got %v", err)
+	} else if _, err = OpenE(box, otherPriv); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("box: expected ErrAuthFailed, got %v", err)
+	} else if _, err = OpenAndVerifyE(box, priv, pub); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("box: expected ErrWrongType, got %v", err)
+	}
+
+	box[len(box)-1] ^= 1
+	if _, err = OpenE(box, priv); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("box: expected ErrAuthFailed, got %v", err)
+	}
+
+	box, err = SignAndSealE(msg, otherPriv, otherPub, pub)
+	if err != nil {
+		t.Fatalf("box: failed to sign and seal message: %v", err)
+	} else if _, err = OpenAndVerifyE(box, priv, otherPub); err != nil {
+		t.Fatalf("box: failed to open signed box: %v", err)
+	} else if _, err = OpenAndVerifyE(box, priv, pub); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("box: expected ErrBadSignature, got %v", err)
+	}
+
+	sig, err := SignE(msg, priv, pub)
+	if err != nil {
+		t.Fatalf("box: failed to sign message: %v", err)
+	} else if err = VerifyE(msg, sig, otherPub); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("box: expected ErrBadSignature, got %v", err)
+	}
+
+	box, err = SealSharedE(msg, []PublicKey{pub})
+	if err != nil {
+		t.Fatalf("box: failed to seal shared box: %v", err)
+	} else if _, err = OpenSharedE(box, priv, pub); err != nil {
+		t.Fatalf("box: failed to open shared box: %v", err)
+	} else if _, err = OpenSharedE(box, otherPriv, otherPub); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("box: expected ErrNotRecipient, got %v", err)
+	} else if _, err = OpenSharedAndVerifyE(box, priv, pub, pub); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("box: expected ErrWrongType, got %v", err)
+	}
+}
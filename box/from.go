@@ -0,0 +1,135 @@
+package box
+
+import "github.com/kisom/aescrypt/secretbox"
+
+// Sender boxes authenticate their sender in the manner of NaCl's
+// crypto_box: the key is derived from an ECDH agreement between the
+// sender's static key and the recipient's key, rather than an
+// ephemeral key. Only the sender and the recipient can compute this
+// key, so a box that opens must have come from one of them. Either
+// could have produced it, so unlike a signed box it does not prove
+// to anyone else who sent the message.
+
+// FromOverhead is the number of bytes of overhead when boxing a
+// message with SealFrom.
+var FromOverhead = secretbox.Overhead + 1
+
+var fromLabel = []byte("box sender")
+
+// publicKey returns the public key for a private key.
+func publicKey(key PrivateKey) (PublicKey, bool) {
+	priv, err := ecdhCurve.NewPrivateKey(key)
+	if err != nil {
+		return nil, false
+	}
+	return priv.PublicKey().Bytes(), true
+}
+
+// directionContext returns the DeriveKey context for messages from
+// sender to recipient.
+func directionContext(sender, recipient PublicKey) []byte {
+	context := make([]byte, 0, len(sender)+len(recipient))
+	context = append(context, sender...)
+	return append(context, recipient...)
+}
+
+// fromKey derives the key for a sender box from the shared key
+// between key and peer, and returns it with the box's associated
+// data. Both bind the box type and the sender's and recipient's
+// public keys, in that order, so that a box cannot be presented as
+// having been sent in the other direction.
+func fromKey(key PrivateKey, peer PublicKey, sending bool) (secretbox.Key, []byte, error) {
+	if !KeyIsSuitable(key, peer) {
+		return nil, nil, ErrInvalidKey
+	}
+
+	own, ok := publicKey(key)
+	if !ok {
+		return nil, nil, ErrInvalidKey
+	}
+	peer, ok = decodeKey(peer)
+	if !ok {
+		return nil, nil, ErrInvalidKey
+	}
+
+	sender, recipient := own, peer
+	if !sending {
+		sender, recipient = peer, own
+	}
+	context := directionContext(sender, recipient)
+
+	shared, ok := SharedKey(key, peer)
+	if !ok {
+		return nil, nil, ErrInvalidKey
+	}
+	defer zero(shared)
+
+	skey, ok := secretbox.DeriveKey(shared, context, fromLabel)
+	if !ok {
+		return nil, nil, ErrInvalidKey
+	}
+	return skey, append([]byte{BoxFrom}, context...), nil
+}
+
+// SealFrom seals a message from the holder of sender to peer. The
+// recipient opens it with OpenFrom, which authenticates the sender
+// without a signature. The box will be FromOverhead bytes longer
+// than the message.
+func SealFrom(message []byte, sender PrivateKey, peer PublicKey) (box []byte, ok bool) {
+	box, err := SealFromE(message, sender, peer)
+	return box, err == nil
+}
+
+// SealFromE seals a message as SealFrom does, returning an error
+// describing why sealing failed rather than a boolean.
+func SealFromE(message []byte, sender PrivateKey, peer PublicKey) (box []byte, err error) {
+	return defaultSealer.SealFromE(message, sender, peer)
+}
+
+func (s *Sealer) sealFrom(message []byte, sender PrivateKey, peer PublicKey) ([]byte, error) {
+	if message == nil {
+		return nil, ErrMalformed
+	}
+
+	skey, ad, err := fromKey(sender, peer, true)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(skey)
+
+	sbox, ok := s.secret.SealWithAD(message, ad, skey)
+	if !ok {
+		return nil, ErrRandomness
+	}
+	return append([]byte{BoxFrom}, sbox...), nil
+}
+
+// OpenFrom opens a box sealed with SealFrom, and returns true only if
+// it was sealed by the holder of the private key for sender.
+func OpenFrom(box []byte, key PrivateKey, sender PublicKey) (message []byte, ok bool) {
+	message, err := OpenFromE(box, key, sender)
+	return message, err == nil
+}
+
+// OpenFromE opens a sender box as OpenFrom does, returning an error
+// describing why opening failed rather than a boolean. A box from a
+// different sender returns ErrAuthFailed.
+func OpenFromE(box []byte, key PrivateKey, sender PublicKey) (message []byte, err error) {
+	if len(box) == 0 {
+		return nil, ErrMalformed
+	} else if box[0] != BoxFrom {
+		return nil, ErrWrongType
+	}
+
+	skey, ad, err := fromKey(key, sender, false)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(skey)
+
+	message, ok := secretbox.OpenWithAD(box[1:], ad, skey)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+	return message, nil
+}
@@ -0,0 +1,61 @@
+package box
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSenderBoxes(t *testing.T) {
+	msg := []byte(testMessages[0])
+	box, ok := SealFrom(msg, testGoodKey, testPeerPub)
+	if !ok {
+		t.Fatal("box: failed to seal sender box")
+	} else if len(box) != len(msg)+FromOverhead {
+		t.Fatalf("box: sender box should be %d bytes, not %d", len(msg)+FromOverhead, len(box))
+	}
+
+	out, ok := OpenFrom(box, testPeerKey, testGoodPub)
+	if !ok {
+		t.Fatal("box: failed to open sender box")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("box: sender box did not round trip")
+	}
+
+	cpub, _ := CompressKey(testGoodPub)
+	if out, ok = OpenFrom(box, testPeerKey, cpub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open sender box with a compressed key")
+	}
+
+	// The sender can compute the same shared key, but the box must
+	// not open as though it had been sent in the other direction.
+	if _, err := OpenFromE(box, testGoodKey, testPeerPub); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("box: reflected sender box should fail authentication, got %v", err)
+	}
+
+	_, other, ok := GenerateKey()
+	if !ok {
+		t.Fatal("box: failed to generate key")
+	} else if _, err := OpenFromE(box, testPeerKey, other); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("box: sender box opened with the wrong sender, got %v", err)
+	}
+
+	box[len(box)-1] ^= 1
+	if _, ok = OpenFrom(box, testPeerKey, testGoodPub); ok {
+		t.Fatal("box: opened a modified sender box")
+	}
+	box[len(box)-1] ^= 1
+
+	sealed, ok := Seal(msg, testPeerPub)
+	if !ok {
+		t.Fatal("box: failed to seal message")
+	}
+
+	if _, ok = Open(box, testPeerKey); ok {
+		t.Fatal("box: Open should not open a sender box")
+	} else if _, err := OpenFromE(sealed, testPeerKey, testGoodPub); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("box: OpenFrom should reject other boxes, got %v", err)
+	} else if _, ok = SealFrom(msg, testGoodKey, testPeerPub[1:]); ok {
+		t.Fatal("box: sealed a sender box to an invalid key")
+	}
+}
@@ -0,0 +1,57 @@
+package box
+
+import (
+	"runtime"
+
+	"github.com/kisom/aescrypt/guard"
+)
+
+// GenerateGuardedKey generates a new key pair as GenerateKey does,
+// returning the private key in locked memory. The private key should
+// be destroyed with its Destroy method when it is no longer needed.
+func GenerateGuardedKey() (key *guard.Key, pub PublicKey, ok bool) {
+	priv, pub, ok := GenerateKey()
+	if !ok {
+		return nil, nil, false
+	}
+
+	key, ok = guard.FromBytes(priv)
+	if !ok {
+		return nil, nil, false
+	}
+	return key, pub, true
+}
+
+// OpenGuarded opens a box as Open does, using a guarded private key.
+func OpenGuarded(box []byte, key *guard.Key) (message []byte, ok bool) {
+	defer runtime.KeepAlive(key)
+	return Open(box, key.Bytes())
+}
+
+// SignAndSealGuarded signs and seals a message as SignAndSeal does,
+// using a guarded private key.
+func SignAndSealGuarded(message []byte, key *guard.Key, public PublicKey, peer PublicKey) (box []byte, ok bool) {
+	defer runtime.KeepAlive(key)
+	return SignAndSeal(message, key.Bytes(), public, peer)
+}
+
+// OpenAndVerifyGuarded opens a signed box as OpenAndVerify does,
+// using a guarded private key.
+func OpenAndVerifyGuarded(box []byte, key *guard.Key, peer PublicKey) (message []byte, ok bool) {
+	defer runtime.KeepAlive(key)
+	return OpenAndVerify(box, key.Bytes(), peer)
+}
+
+// OpenSharedGuarded opens a shared box as OpenShared does, using a
+// guarded private key.
+func OpenSharedGuarded(box []byte, key *guard.Key, public PublicKey) (message []byte, ok bool) {
+	defer runtime.KeepAlive(key)
+	return OpenShared(box, key.Bytes(), public)
+}
+
+// OpenSharedAndVerifyGuarded opens a signed shared box as
+// OpenSharedAndVerify does, using a guarded private key.
+func OpenSharedAndVerifyGuarded(box []byte, key *guard.Key, public PublicKey, signer PublicKey) (message []byte, ok bool) {
+	defer runtime.KeepAlive(key)
+	return OpenSharedAndVerify(box, key.Bytes(), public, signer)
+}
@@ -0,0 +1,40 @@
+package box
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGuardedBoxing(t *testing.T) {
+	key, pub, ok := GenerateGuardedKey()
+	if !ok {
+		t.Fatal("box: failed to generate guarded key")
+	}
+	msg := []byte(testMessages[0])
+
+	box, ok := Seal(msg, pub)
+	if !ok {
+		t.Fatal("box: failed to seal message")
+	} else if out, ok := OpenGuarded(box, key); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open with guarded key")
+	}
+
+	box, ok = SignAndSealGuarded(msg, key, pub, pub)
+	if !ok {
+		t.Fatal("box: failed to sign and seal with guarded key")
+	} else if out, ok := OpenAndVerifyGuarded(box, key, pub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open signed box with guarded key")
+	}
+
+	box, ok = SealShared(msg, []PublicKey{pub})
+	if !ok {
+		t.Fatal("box: failed to seal shared box")
+	} else if out, ok := OpenSharedGuarded(box, key, pub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("box: failed to open shared box with guarded key")
+	}
+
+	key.Destroy()
+	if _, ok = OpenSharedGuarded(box, key, pub); ok {
+		t.Fatal("box: opened with a destroyed key")
+	}
+}
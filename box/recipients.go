@@ -0,0 +1,328 @@
+package box
+
+import (
+	"bytes"
+
+	"github.com/kisom/aescrypt/secretbox"
+)
+
+// A shared box seals its message once, under a random content key,
+// and wraps the content key for each peer under a key agreed with the
+// box's ephemeral key. The functions in this file change the peers of
+// a shared box by rewriting its peer list, without necessarily
+// re-encrypting the message. A signed shared box remains signed: the
+// signature is sealed with the message. Anonymous shared boxes do not
+// list their peers, and so cannot be changed.
+
+// sharedBox is a parsed shared box. The peers are in the box's key
+// encoding.
+type sharedBox struct {
+	btype      byte
+	compressed bool
+	ephemeral  PublicKey
+	peers      []PublicKey
+	entries    [][]byte
+	sbox       []byte
+}
+
+func parseSharedBox(box []byte) (*sharedBox, error) {
+	btype, body, compressed, ok := boxHeader(box)
+	if !ok {
+		return nil, ErrMalformed
+	} else if btype != BoxShared && btype != BoxSharedSigned {
+		return nil, ErrWrongType
+	}
+
+	sb := &sharedBox{btype: btype, compressed: compressed}
+	unpacker := newbr(body)
+	sb.ephemeral = unpacker.Next()
+	if sb.ephemeral == nil {
+		return nil, ErrMalformed
+	} else if len(sb.ephemeral) != keySizeFor(compressed) {
+		return nil, ErrMalformed
+	}
+
+	if err := sb.readPeers(unpacker.Next()); err != nil {
+		return nil, err
+	}
+
+	sb.sbox = unpacker.Next()
+	if sb.sbox == nil {
+		return nil, ErrMalformed
+	}
+	return sb, nil
+}
+
+// readPeers reads the box's peers and their entries from a packed
+// peer list.
+func (sb *sharedBox) readPeers(packedPeers []byte) error {
+	if packedPeers == nil || packedPeers[0] != peerList {
+		return ErrMalformed
+	}
+	peerUnpack := newbr(packedPeers[1:])
+	peerCount, ok := peerUnpack.NextU32()
+	if !ok {
+		return ErrMalformed
+	}
+
+	for i := uint32(0); i < peerCount; i++ {
+		peer := peerUnpack.Next()
+		entry := peerUnpack.Next()
+		if peer == nil || entry == nil {
+			return ErrMalformed
+		}
+		sb.peers = append(sb.peers, peer)
+		sb.entries = append(sb.entries, entry)
+	}
+	return nil
+}
+
+func (sb *sharedBox) bytes() ([]byte, error) {
+	packPeers := newbw([]byte{peerList})
+	packPeers.WriteUint32(uint32(len(sb.peers)))
+	for i := range sb.peers {
+		packPeers.Write(sb.peers[i])
+		packPeers.Write(sb.entries[i])
+	}
+	plist := packPeers.Bytes()
+	if plist == nil {
+		return nil, ErrMalformed
+	}
+
+	packer := newbw(sb.header())
+	packer.Write(sb.ephemeral)
+	packer.Write(plist)
+	packer.Write(sb.sbox)
+	box := packer.Bytes()
+	if box == nil {
+		return nil, ErrMalformed
+	}
+	return box, nil
+}
+
+func (sb *sharedBox) header() []byte {
+	if sb.compressed {
+		return []byte{BoxCompressed, sb.btype}
+	}
+	return []byte{sb.btype}
+}
+
+// indexOf returns the index of peer in peers, or -1.
+func indexOf(peers []PublicKey, peer PublicKey) int {
+	for i := range peers {
+		if bytes.Equal(peers[i], peer) {
+			return i
+		}
+	}
+	return -1
+}
+
+// contentKey unwraps the box's content key as the holder of key, and
+// checks that it opens the box.
+func (sb *sharedBox) contentKey(key PrivateKey, public PublicKey) (secretbox.Key, error) {
+	if !KeyIsSuitable(key, public) {
+		return nil, ErrInvalidKey
+	}
+	public, ok := encodeKey(public, sb.compressed)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	i := indexOf(sb.peers, public)
+	if i < 0 {
+		return nil, ErrNotRecipient
+	}
+
+	skey, ok := keyExchange(key, sb.ephemeral)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	defer zero(skey)
+
+	shared, ok := secretbox.Open(sb.entries[i], skey)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+
+	message, ok := secretbox.Open(sb.sbox, shared)
+	if !ok {
+		zero(shared)
+		return nil, ErrAuthFailed
+	}
+	zero(message)
+	return shared, nil
+}
+
+// rewrap replaces the box's ephemeral key and peer list, wrapping
+// shared for each of peers.
+func (s *Sealer) rewrap(sb *sharedBox, shared secretbox.Key, peers []PublicKey) error {
+	e_priv, e_pub, ok := s.generateKey()
+	if !ok {
+		return ErrRandomness
+	}
+	defer zero(e_priv)
+
+	sb.ephemeral, ok = encodeKey(e_pub, sb.compressed)
+	if !ok {
+		return ErrInvalidKey
+	}
+
+	sb.peers, sb.entries = nil, nil
+	for _, peer := range peers {
+		entry, ok := s.boxForPeer(e_priv, peer, shared)
+		if !ok {
+			return ErrInvalidKey
+		}
+		sb.peers = append(sb.peers, peer)
+		sb.entries = append(sb.entries, entry)
+	}
+	return nil
+}
+
+// AddRecipients adds peers to a shared box, acting as the holder of
+// key, who must be one of the box's recipients. The message is not
+// re-encrypted: the content key is unwrapped and wrapped again for
+// the existing and new peers under a fresh ephemeral key. Peers that
+// are already recipients are skipped.
+func AddRecipients(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, bool) {
+	box, err := AddRecipientsE(box, key, public, peers)
+	return box, err == nil
+}
+
+// AddRecipientsE adds peers to a shared box as AddRecipients does,
+// returning an error describing why the box could not be changed
+// rather than a boolean.
+func AddRecipientsE(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, error) {
+	return defaultSealer.AddRecipientsE(box, key, public, peers)
+}
+
+func (s *Sealer) addRecipients(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, error) {
+	sb, err := parseSharedBox(box)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := sb.contentKey(key, public)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(shared)
+
+	all := append([]PublicKey{}, sb.peers...)
+	for _, peer := range peers {
+		peer, ok := encodeKey(peer, sb.compressed)
+		if !ok {
+			return nil, ErrInvalidKey
+		} else if indexOf(all, peer) >= 0 {
+			continue
+		}
+		all = append(all, peer)
+	}
+
+	if err = s.rewrap(sb, shared, all); err != nil {
+		return nil, err
+	}
+	return sb.bytes()
+}
+
+// RemoveRecipients removes peers from a shared box by dropping their
+// entries from its peer list. It needs no private key, and neither
+// the content key nor the message is changed, so a removed peer that
+// has kept an earlier copy of the box can still recover the message;
+// RemoveRecipientsAndRekey re-encrypts the message to prevent this.
+// At least one recipient must remain.
+func RemoveRecipients(box []byte, peers []PublicKey) ([]byte, bool) {
+	box, err := RemoveRecipientsE(box, peers)
+	return box, err == nil
+}
+
+// RemoveRecipientsE removes peers from a shared box as
+// RemoveRecipients does, returning an error describing why the box
+// could not be changed rather than a boolean.
+func RemoveRecipientsE(box []byte, peers []PublicKey) ([]byte, error) {
+	sb, err := parseSharedBox(box)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = sb.remove(peers); err != nil {
+		return nil, err
+	}
+	return sb.bytes()
+}
+
+// remove drops peers from the box's peer list.
+func (sb *sharedBox) remove(peers []PublicKey) error {
+	for _, peer := range peers {
+		peer, ok := encodeKey(peer, sb.compressed)
+		if !ok {
+			return ErrInvalidKey
+		}
+
+		if i := indexOf(sb.peers, peer); i >= 0 {
+			sb.peers = append(sb.peers[:i], sb.peers[i+1:]...)
+			sb.entries = append(sb.entries[:i], sb.entries[i+1:]...)
+		}
+	}
+
+	if len(sb.peers) == 0 {
+		return ErrNotRecipient
+	}
+	return nil
+}
+
+// RemoveRecipientsAndRekey removes peers from a shared box, acting as
+// the holder of key, who must be one of the box's recipients. The
+// message is re-encrypted under a fresh content key, which is wrapped
+// for the remaining peers under a fresh ephemeral key, so that a
+// removed peer cannot open the new box even with the old content key.
+func RemoveRecipientsAndRekey(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, bool) {
+	box, err := RemoveRecipientsAndRekeyE(box, key, public, peers)
+	return box, err == nil
+}
+
+// RemoveRecipientsAndRekeyE removes peers from a shared box as
+// RemoveRecipientsAndRekey does, returning an error describing why
+// the box could not be changed rather than a boolean.
+func RemoveRecipientsAndRekeyE(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, error) {
+	return defaultSealer.RemoveRecipientsAndRekeyE(box, key, public, peers)
+}
+
+func (s *Sealer) removeAndRekey(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, error) {
+	sb, err := parseSharedBox(box)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := sb.contentKey(key, public)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(shared)
+
+	message, ok := secretbox.Open(sb.sbox, shared)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+	defer zero(message)
+
+	if err = sb.remove(peers); err != nil {
+		return nil, err
+	}
+
+	rekeyed, ok := s.secret.GenerateKey()
+	if !ok {
+		return nil, ErrRandomness
+	}
+	defer zero(rekeyed)
+
+	sb.sbox, ok = s.secret.Seal(message, rekeyed)
+	if !ok {
+		return nil, ErrRandomness
+	}
+
+	if err = s.rewrap(sb, rekeyed, append([]PublicKey{}, sb.peers...)); err != nil {
+		return nil, err
+	}
+	return sb.bytes()
+}
@@ -0,0 +1,232 @@
+package box
+
+import (
+	"io"
+
+	"github.com/kisom/aescrypt/secretbox"
+)
+
+// Options configures a Sealer.
+type Options struct {
+	// Rand is the source of random data for keys and boxes. If it
+	// is nil, the package-level PRNG is used, and the symmetric
+	// boxes inside a box use the PRNG of secretbox or gcmbox.
+	// Signatures are made by crypto/ecdsa, which since Go 1.26
+	// ignores Rand and always uses a secure source of its own.
+	Rand io.Reader
+
+	// CompressKeys selects compressed public keys: the Sealer's
+	// GenerateKey returns compressed public keys, and its boxes
+	// carry the BoxCompressed version byte and compressed keys.
+	// Open accepts boxes in either encoding.
+	CompressKeys bool
+}
+
+// A Sealer generates keys and seals boxes using its own source of
+// random data, rather than the package-level PRNG. A Sealer is safe
+// for concurrent use if its source of random data is.
+// The package-level functions that need random data use a default
+// Sealer; opening boxes and verifying signatures need none, and so
+// are only provided as package-level functions.
+type Sealer struct {
+	rand     io.Reader
+	compress bool
+	secret   *secretbox.Sealer
+}
+
+var defaultSealer = NewSealer(nil)
+
+// NewSealer returns a Sealer configured by opts, which may be nil.
+func NewSealer(opts *Options) *Sealer {
+	s := &Sealer{}
+	if opts != nil {
+		s.rand = opts.Rand
+		s.compress = opts.CompressKeys
+	}
+	s.secret = secretbox.NewSealer(&secretbox.Options{Rand: s.rand})
+	return s
+}
+
+func (s *Sealer) reader() io.Reader {
+	if s.rand == nil {
+		return PRNG
+	}
+	return s.rand
+}
+
+// header returns the leading bytes of a box of the given type.
+func (s *Sealer) header(btype byte) []byte {
+	if s.compress {
+		return []byte{BoxCompressed, btype}
+	}
+	return []byte{btype}
+}
+
+// GenerateKey generates a key pair as the package-level GenerateKey
+// does.
+func (s *Sealer) GenerateKey() (PrivateKey, PublicKey, bool) {
+	return s.generateKey()
+}
+
+// Seal seals a message as the package-level Seal does.
+func (s *Sealer) Seal(message []byte, peer PublicKey) (box []byte, ok bool) {
+	box, err := s.SealE(message, peer)
+	return box, err == nil
+}
+
+// SealE seals a message as the package-level SealE does.
+func (s *Sealer) SealE(message []byte, peer PublicKey) (box []byte, err error) {
+	return s.sealBox(message, peer, BoxUnsigned)
+}
+
+// SealGCM seals a message as the package-level SealGCM does.
+func (s *Sealer) SealGCM(message []byte, peer PublicKey) (box []byte, ok bool) {
+	box, err := s.sealBox(message, peer, BoxUnsignedGCM)
+	return box, err == nil
+}
+
+// SealFrom seals a sender box as the package-level SealFrom does.
+func (s *Sealer) SealFrom(message []byte, sender PrivateKey, peer PublicKey) (box []byte, ok bool) {
+	box, err := s.SealFromE(message, sender, peer)
+	return box, err == nil
+}
+
+// SealFromE seals a sender box as the package-level SealFromE does.
+func (s *Sealer) SealFromE(message []byte, sender PrivateKey, peer PublicKey) (box []byte, err error) {
+	return s.sealFrom(message, sender, peer)
+}
+
+// Sign signs a message as the package-level Sign does.
+func (s *Sealer) Sign(message []byte, key PrivateKey, pub PublicKey) (signature []byte, ok bool) {
+	signature, err := s.SignE(message, key, pub)
+	return signature, err == nil
+}
+
+// SignE signs a message as the package-level SignE does.
+func (s *Sealer) SignE(message []byte, key PrivateKey, pub PublicKey) (signature []byte, err error) {
+	return s.sign(message, key, pub)
+}
+
+// SignAndSeal signs and seals a message as the package-level
+// SignAndSeal does.
+func (s *Sealer) SignAndSeal(message []byte, key PrivateKey, public PublicKey, peer PublicKey) (box []byte, ok bool) {
+	box, err := s.SignAndSealE(message, key, public, peer)
+	return box, err == nil
+}
+
+// SignAndSealE signs and seals a message as the package-level
+// SignAndSealE does.
+func (s *Sealer) SignAndSealE(message []byte, key PrivateKey, public PublicKey, peer PublicKey) (box []byte, err error) {
+	signedMessage, err := s.signMessage(message, key, public)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(signedMessage)
+	return s.sealBox(signedMessage, peer, BoxSigned)
+}
+
+// SealShared seals a shared box as the package-level SealShared does.
+func (s *Sealer) SealShared(message []byte, peers []PublicKey) (box []byte, ok bool) {
+	box, err := s.SealSharedE(message, peers)
+	return box, err == nil
+}
+
+// SealSharedE seals a shared box as the package-level SealSharedE
+// does.
+func (s *Sealer) SealSharedE(message []byte, peers []PublicKey) (box []byte, err error) {
+	return s.buildSharedBox(message, peers, BoxShared)
+}
+
+// SignAndSealShared signs and seals a shared box as the
+// package-level SignAndSealShared does.
+func (s *Sealer) SignAndSealShared(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, ok bool) {
+	box, err := s.SignAndSealSharedE(message, peers, sigkey, sigpub)
+	return box, err == nil
+}
+
+// SignAndSealSharedE signs and seals a shared box as the
+// package-level SignAndSealSharedE does.
+func (s *Sealer) SignAndSealSharedE(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, err error) {
+	signedMessage, err := s.signMessage(message, sigkey, sigpub)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(signedMessage)
+	return s.buildSharedBox(signedMessage, peers, BoxSharedSigned)
+}
+
+// SealSharedAnonymous seals an anonymous shared box as the
+// package-level SealSharedAnonymous does.
+func (s *Sealer) SealSharedAnonymous(message []byte, peers []PublicKey) (box []byte, ok bool) {
+	box, err := s.SealSharedAnonymousE(message, peers)
+	return box, err == nil
+}
+
+// SealSharedAnonymousE seals an anonymous shared box as the
+// package-level SealSharedAnonymousE does.
+func (s *Sealer) SealSharedAnonymousE(message []byte, peers []PublicKey) (box []byte, err error) {
+	return s.buildSharedBox(message, peers, BoxSharedAnonymous)
+}
+
+// SignAndSealSharedAnonymous signs and seals an anonymous shared box
+// as the package-level SignAndSealSharedAnonymous does.
+func (s *Sealer) SignAndSealSharedAnonymous(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, ok bool) {
+	box, err := s.SignAndSealSharedAnonymousE(message, peers, sigkey, sigpub)
+	return box, err == nil
+}
+
+// SignAndSealSharedAnonymousE signs and seals an anonymous shared box
+// as the package-level SignAndSealSharedAnonymousE does.
+func (s *Sealer) SignAndSealSharedAnonymousE(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey) (box []byte, err error) {
+	signedMessage, err := s.signMessage(message, sigkey, sigpub)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(signedMessage)
+	return s.buildSharedBox(signedMessage, peers, BoxSharedAnonymousSigned)
+}
+
+// SealThreshold seals a threshold box as the package-level
+// SealThreshold does.
+func (s *Sealer) SealThreshold(message []byte, peers []PublicKey, k int) (box []byte, ok bool) {
+	box, err := s.SealThresholdE(message, peers, k)
+	return box, err == nil
+}
+
+// SealThresholdE seals a threshold box as the package-level
+// SealThresholdE does.
+func (s *Sealer) SealThresholdE(message []byte, peers []PublicKey, k int) (box []byte, err error) {
+	return s.sealThreshold(message, peers, k)
+}
+
+// AddRecipients adds peers to a shared box as the package-level
+// AddRecipients does.
+func (s *Sealer) AddRecipients(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, bool) {
+	box, err := s.AddRecipientsE(box, key, public, peers)
+	return box, err == nil
+}
+
+// AddRecipientsE adds peers to a shared box as the package-level
+// AddRecipientsE does.
+func (s *Sealer) AddRecipientsE(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, error) {
+	return s.addRecipients(box, key, public, peers)
+}
+
+// RemoveRecipientsAndRekey removes peers from a shared box as the
+// package-level RemoveRecipientsAndRekey does.
+func (s *Sealer) RemoveRecipientsAndRekey(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, bool) {
+	box, err := s.RemoveRecipientsAndRekeyE(box, key, public, peers)
+	return box, err == nil
+}
+
+// RemoveRecipientsAndRekeyE removes peers from a shared box as the
+// package-level RemoveRecipientsAndRekeyE does.
+func (s *Sealer) RemoveRecipientsAndRekeyE(box []byte, key PrivateKey, public PublicKey, peers []PublicKey) ([]byte, error) {
+	return s.removeAndRekey(box, key, public, peers)
+}
+
+// SignKey signs a peer's public key as the package-level SignKey
+// does.
+func (s *Sealer) SignKey(priv PrivateKey, pub, peer PublicKey) (sig []byte, ok bool) {
+	return s.signKey(priv, pub, peer)
+}
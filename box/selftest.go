@@ -0,0 +1,80 @@
+package box
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+// Known-answer test vectors: two key pairs, the shared key they
+// agree on, and a signature over selfTestMessage by the first.
+var (
+	selfTestMessage = []byte("cryptobox power-on self-test")
+	selfTestPriv1   = "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
+	selfTestPub1    = "04515c3d6eb9e396b904d3feca7f54fdcd0cc1e997bf375dca515ad0a6c3b403" +
+		"5f4536be3a50f318fbf9a5475902a221502bef0d57e08c53b2cc0a56f17d9f93" +
+		"54"
+	selfTestPriv2 = "2122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f40"
+	selfTestPub2  = "041f140146bfb1b251f84f4ddbe0d4cdcfd77afd984a9520e35794021f8312bb" +
+		"9eec995a08b1fa7704df3dcc0b50a9665263fb7711f95f9f8a449c5096e47c89" +
+		"2b"
+	selfTestShared = "6fd686c3dc30571c94d9b0578e18cd3b3ddc24b275e54be55827ba7869d4c8e4" +
+		"6217a2e931d1b73cc38643c4b3bed4c4"
+	selfTestSig = "000000201240d87189b4a3f7f3557bfd45461add62b68f4c9792a1eada657952" +
+		"86096bcf0000002042f2c01452ee2f35ac47fae2fc3a0b3e5987c3b3fa1cf380" +
+		"b9015b480fc5744d"
+)
+
+// SelfTest runs known-answer tests of the ECDH key agreement and
+// signature verification behind the package, and a pairwise
+// consistency test of signing. If a test fails, it returns an error
+// wrapping ErrSelfTest, and every package in cryptobox refuses to
+// seal or open boxes from then on. Building with the
+// cryptobox_selftest tag runs SelfTest when the package is
+// initialised.
+func SelfTest() error {
+	if err := selfTest(); err != nil {
+		selftest.Fail()
+		return fmt.Errorf("%w: %v", ErrSelfTest, err)
+	}
+	return nil
+}
+
+func selfTest() error {
+	var vectors [6][]byte
+	for i, v := range []string{selfTestPriv1, selfTestPub1, selfTestPriv2, selfTestPub2, selfTestShared, selfTestSig} {
+		b, err := hex.DecodeString(v)
+		if err != nil {
+			return err
+		}
+		vectors[i] = b
+	}
+	priv1, pub1, priv2, pub2 := vectors[0], vectors[1], vectors[2], vectors[3]
+	shared, sig := vectors[4], vectors[5]
+
+	skey, ok := keyExchange(priv1, pub2)
+	if !ok || !bytes.Equal(skey, shared) {
+		return errors.New("ECDH known-answer test failed")
+	}
+	skey, ok = keyExchange(priv2, pub1)
+	if !ok || !bytes.Equal(skey, shared) {
+		return errors.New("ECDH known-answer test failed")
+	}
+
+	if err := VerifyE(selfTestMessage, sig, pub1); err != nil {
+		return errors.New("signature verification known-answer test failed")
+	} else if err = VerifyE(selfTestMessage, sig, pub2); err == nil {
+		return errors.New("signature verification accepted an invalid signature")
+	}
+
+	sig, err := SignE(selfTestMessage, priv1, pub1)
+	if err != nil {
+		return err
+	} else if err = VerifyE(selfTestMessage, sig, pub1); err != nil {
+		return errors.New("signature pairwise consistency test failed")
+	}
+	return nil
+}
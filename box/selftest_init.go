@@ -0,0 +1,7 @@
+//go:build cryptobox_selftest
+
+package box
+
+func init() {
+	SelfTest()
+}
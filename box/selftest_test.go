@@ -0,0 +1,30 @@
+package box
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("box: self-test failed: %v", err)
+	}
+
+	defer func(v string) { selfTestShared = v }(selfTestShared)
+	defer selftest.Reset()
+
+	b := []byte(selfTestShared)
+	b[0] ^= 1
+	selfTestShared = string(b)
+	if err := SelfTest(); !errors.Is(err, ErrSelfTest) {
+		t.Fatalf("box: expected ErrSelfTest, got %v", err)
+	}
+
+	if _, ok := Seal([]byte(testMessages[0]), testGoodPub); ok {
+		t.Fatal("box: sealed after a self-test failure")
+	} else if Verify([]byte(testMessages[0]), make([]byte, 8), testGoodPub) {
+		t.Fatal("box: verified a signature after a self-test failure")
+	}
+}
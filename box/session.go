@@ -0,0 +1,189 @@
+package box
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/kisom/aescrypt/secretbox"
+)
+
+const counterSize = 8
+
+var sessionLabel = []byte("box session")
+
+// SessionOptions configures a Session.
+type SessionOptions struct {
+	// Rand is the source of random data for the session's boxes. If
+	// it is nil, secretbox's PRNG is used.
+	Rand io.Reader
+
+	// Counter numbers each message sealed by the session. The number
+	// is authenticated with the message, and Open only accepts
+	// messages numbered higher than the last one it opened, so that
+	// replayed, reordered and dropped-then-reinserted messages are
+	// rejected. Both ends of the session must agree on this option.
+	Counter bool
+}
+
+// A Session seals and opens any number of messages between two
+// parties, performing the ECDH key agreement only once. The shared
+// key is expanded with secretbox.DeriveKey into a key for each
+// direction, bound to the public keys of the sender and the
+// recipient, so a message cannot be reflected back to its sender and
+// the session keys are independent of the keys used by other boxes
+// between the same two parties. A Session is safe for concurrent use.
+type Session struct {
+	lock     sync.Mutex
+	send     secretbox.Key
+	recv     secretbox.Key
+	counter  bool
+	sent     uint64
+	received uint64
+	secret   *secretbox.Sealer
+}
+
+// NewSession returns a session between the holder of key and peer,
+// and a boolean indicating success. The peer builds the matching
+// session from its own private key and the public key for key.
+func NewSession(key PrivateKey, peer PublicKey, opts *SessionOptions) (*Session, bool) {
+	if !KeyIsSuitable(key, peer) {
+		return nil, false
+	}
+
+	own, ok := publicKey(key)
+	if !ok {
+		return nil, false
+	}
+	peer, ok = decodeKey(peer)
+	if !ok {
+		return nil, false
+	}
+
+	shared, ok := SharedKey(key, peer)
+	if !ok {
+		return nil, false
+	}
+	defer zero(shared)
+
+	send, ok := secretbox.DeriveKey(shared, directionContext(own, peer), sessionLabel)
+	if !ok {
+		return nil, false
+	}
+	recv, ok := secretbox.DeriveKey(shared, directionContext(peer, own), sessionLabel)
+	if !ok {
+		zero(send)
+		return nil, false
+	}
+
+	s := &Session{send: send, recv: recv}
+	var r io.Reader
+	if opts != nil {
+		r = opts.Rand
+		s.counter = opts.Counter
+	}
+	s.secret = secretbox.NewSealer(&secretbox.Options{Rand: r})
+	return s, true
+}
+
+// Overhead returns the number of bytes of overhead when sealing a
+// message with the session.
+func (s *Session) Overhead() int {
+	if s.counter {
+		return secretbox.Overhead + counterSize
+	}
+	return secretbox.Overhead
+}
+
+// Seal seals a message for the peer, returning the box and a boolean
+// indicating success.
+func (s *Session) Seal(message []byte) (box []byte, ok bool) {
+	box, err := s.SealE(message)
+	return box, err == nil
+}
+
+// SealE seals a message as Seal does, returning an error describing
+// why sealing failed rather than a boolean. If the session's counter
+// is exhausted, it returns ErrCounterExhausted.
+func (s *Session) SealE(message []byte) (box []byte, err error) {
+	if message == nil {
+		return nil, ErrMalformed
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.send == nil {
+		return nil, ErrInvalidKey
+	} else if !s.counter {
+		box, ok := s.secret.Seal(message, s.send)
+		if !ok {
+			return nil, ErrRandomness
+		}
+		return box, nil
+	}
+
+	if s.sent == ^uint64(0) {
+		return nil, ErrCounterExhausted
+	}
+	ctr := make([]byte, counterSize)
+	binary.BigEndian.PutUint64(ctr, s.sent+1)
+
+	sbox, ok := s.secret.SealWithAD(message, ctr, s.send)
+	if !ok {
+		return nil, ErrRandomness
+	}
+	s.sent++
+	return append(ctr, sbox...), nil
+}
+
+// Open opens a box sealed by the peer's session, returning the
+// message and a boolean indicating success.
+func (s *Session) Open(box []byte) (message []byte, ok bool) {
+	message, err := s.OpenE(box)
+	return message, err == nil
+}
+
+// OpenE opens a box as Open does, returning an error describing why
+// opening failed rather than a boolean. With a counter, a box that
+// is authentic but not numbered higher than the last box opened
+// returns ErrReplay.
+func (s *Session) OpenE(box []byte) (message []byte, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.recv == nil {
+		return nil, ErrInvalidKey
+	} else if !s.counter {
+		message, ok := secretbox.Open(box, s.recv)
+		if !ok {
+			return nil, ErrAuthFailed
+		}
+		return message, nil
+	}
+
+	if len(box) < counterSize {
+		return nil, ErrMalformed
+	}
+	ctr := box[:counterSize]
+	message, ok := secretbox.OpenWithAD(box[counterSize:], ctr, s.recv)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+
+	n := binary.BigEndian.Uint64(ctr)
+	if n <= s.received {
+		zero(message)
+		return nil, ErrReplay
+	}
+	s.received = n
+	return message, nil
+}
+
+// Destroy wipes the session's keys. The session cannot be used
+// afterwards.
+func (s *Session) Destroy() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	zero(s.send)
+	zero(s.recv)
+	s.send, s.recv = nil, nil
+}
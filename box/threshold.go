@@ -0,0 +1,274 @@
+package box
+
+import (
+	"github.com/kisom/aescrypt/internal/selftest"
+	"github.com/kisom/aescrypt/secretbox"
+	"github.com/kisom/aescrypt/shamir"
+)
+
+// A threshold box is a shared box that needs k of its n peers to
+// cooperate to open it. The content key is split with Shamir's secret
+// sharing, and each peer's entry in the peer list wraps one share
+// rather than the key. Each peer recovers its share with
+// OpenThresholdShare, and any k shares are given to CombineThreshold
+// to open the box. Shares are secret: they should only be passed to
+// the party combining them over a secure channel, such as a box.
+//
+// The secret that is split is not used as the content key directly,
+// but as the master key for DeriveKey. A secretbox key's tag does not
+// cover its encryption key, so a share that changed only that half of
+// the key would otherwise open the box to the wrong message; deriving
+// the key means that any change to the secret causes the box to fail
+// authentication.
+
+var thresholdLabel = []byte("box threshold")
+
+// thresholdKey derives the content key of a threshold box from the
+// secret shared between its peers.
+func thresholdKey(secret []byte) (secretbox.Key, bool) {
+	return secretbox.DeriveKey(secret, nil, thresholdLabel)
+}
+
+func (s *Sealer) sealThreshold(message []byte, peers []PublicKey, k int) ([]byte, error) {
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	} else if message == nil {
+		return nil, ErrMalformed
+	} else if len(peers) > shamir.MaxShares {
+		return nil, ErrMalformed
+	} else if k < 1 || k > len(peers) {
+		return nil, ErrTooFewShares
+	}
+
+	for _, peer := range peers {
+		if !KeyIsSuitable(nil, peer) {
+			return nil, ErrInvalidKey
+		}
+	}
+
+	e_priv, e_pub, ok := s.generateKey()
+	if !ok {
+		return nil, ErrRandomness
+	}
+	defer zero(e_priv)
+
+	secret, ok := s.secret.GenerateKey()
+	if !ok {
+		return nil, ErrRandomness
+	}
+	defer zero(secret)
+
+	shared, ok := thresholdKey(secret)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	defer zero(shared)
+
+	shares, ok := shamir.SplitFrom(s.reader(), secret, len(peers), k)
+	if !ok {
+		return nil, ErrRandomness
+	}
+	defer func() {
+		for _, share := range shares {
+			zero(share)
+		}
+	}()
+
+	packPeers := newbw([]byte{peerList})
+	packPeers.WriteUint32(uint32(len(peers)))
+	for i, peer := range peers {
+		entry, ok := encodeKey(peer, s.compress)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		packPeers.Write(entry)
+		pbox, ok := s.boxForPeer(e_priv, peer, shares[i])
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		packPeers.Write(pbox)
+	}
+	plist := packPeers.Bytes()
+	if plist == nil {
+		return nil, ErrMalformed
+	}
+
+	packer := newbw(s.header(BoxThreshold))
+	packer.Write(e_pub)
+	packer.WriteUint32(uint32(k))
+	packer.Write(plist)
+
+	// The message is bound to the rest of the box, so that neither
+	// its threshold nor its peers can be changed.
+	ad := append([]byte{}, packer.Bytes()...)
+	sbox, ok := s.secret.SealWithAD(message, ad, shared)
+	if !ok {
+		return nil, ErrRandomness
+	}
+	packer.Write(sbox)
+	box := packer.Bytes()
+	if box == nil {
+		return nil, ErrMalformed
+	}
+	return box, nil
+}
+
+// SealThreshold seals a message for peers, such that any k of them
+// must cooperate to open it. There may be at most shamir.MaxShares
+// peers. It returns the box and a boolean indicating whether sealing
+// was successful.
+func SealThreshold(message []byte, peers []PublicKey, k int) (box []byte, ok bool) {
+	box, err := SealThresholdE(message, peers, k)
+	return box, err == nil
+}
+
+// SealThresholdE seals a threshold box as SealThreshold does,
+// returning an error describing why sealing failed rather than a
+// boolean.
+func SealThresholdE(message []byte, peers []PublicKey, k int) (box []byte, err error) {
+	return defaultSealer.SealThresholdE(message, peers, k)
+}
+
+// thresholdBox is a parsed threshold box.
+type thresholdBox struct {
+	sharedBox
+	k int
+
+	// ad is the part of the box that precedes the sealed message.
+	ad []byte
+}
+
+func parseThresholdBox(box []byte) (*thresholdBox, error) {
+	btype, body, compressed, ok := boxHeader(box)
+	if !ok {
+		return nil, ErrMalformed
+	} else if btype != BoxThreshold {
+		return nil, ErrWrongType
+	}
+
+	tb := &thresholdBox{}
+	tb.btype, tb.compressed = btype, compressed
+	unpacker := newbr(body)
+	tb.ephemeral = unpacker.Next()
+	if tb.ephemeral == nil {
+		return nil, ErrMalformed
+	} else if len(tb.ephemeral) != keySizeFor(compressed) {
+		return nil, ErrMalformed
+	}
+
+	k, ok := unpacker.NextU32()
+	if !ok || k == 0 {
+		return nil, ErrMalformed
+	}
+	tb.k = int(k)
+
+	if err := tb.readPeers(unpacker.Next()); err != nil {
+		return nil, err
+	}
+
+	tb.ad = box[:len(box)-unpacker.Len()]
+	tb.sbox = unpacker.Next()
+	if tb.sbox == nil {
+		return nil, ErrMalformed
+	}
+	return tb, nil
+}
+
+// BoxThresholdShares returns the number of shares needed to open a
+// threshold box, and false if box is not a threshold box.
+func BoxThresholdShares(box []byte) (k int, ok bool) {
+	tb, err := parseThresholdBox(box)
+	if err != nil {
+		return 0, false
+	}
+	return tb.k, true
+}
+
+// OpenThresholdShare recovers the holder of key's share of a
+// threshold box. It returns the share and a boolean indicating
+// success.
+func OpenThresholdShare(box []byte, key PrivateKey, public PublicKey) (share []byte, ok bool) {
+	share, err := OpenThresholdShareE(box, key, public)
+	return share, err == nil
+}
+
+// OpenThresholdShareE recovers a share of a threshold box as
+// OpenThresholdShare does, returning an error describing why it
+// failed rather than a boolean. If public is not one of the box's
+// peers, it returns ErrNotRecipient.
+func OpenThresholdShareE(box []byte, key PrivateKey, public PublicKey) (share []byte, err error) {
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	} else if !KeyIsSuitable(key, public) {
+		return nil, ErrInvalidKey
+	}
+
+	tb, err := parseThresholdBox(box)
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := encodeKey(public, tb.compressed)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	i := indexOf(tb.peers, public)
+	if i < 0 {
+		return nil, ErrNotRecipient
+	}
+
+	skey, ok := keyExchange(key, tb.ephemeral)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	defer zero(skey)
+
+	share, ok = secretbox.Open(tb.entries[i], skey)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+	return share, nil
+}
+
+// CombineThreshold opens a threshold box using shares recovered by
+// OpenThresholdShare. At least as many shares as the box needs must
+// be given. It returns the message and a boolean indicating success.
+func CombineThreshold(box []byte, shares [][]byte) (message []byte, ok bool) {
+	message, err := CombineThresholdE(box, shares)
+	return message, err == nil
+}
+
+// CombineThresholdE opens a threshold box as CombineThreshold does,
+// returning an error describing why opening failed rather than a
+// boolean. If too few shares are given, it returns ErrTooFewShares;
+// if a share is invalid, it returns ErrAuthFailed.
+func CombineThresholdE(box []byte, shares [][]byte) (message []byte, err error) {
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	}
+
+	tb, err := parseThresholdBox(box)
+	if err != nil {
+		return nil, err
+	} else if len(shares) < tb.k {
+		return nil, ErrTooFewShares
+	}
+
+	secret, ok := shamir.Combine(shares)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	defer zero(secret)
+
+	shared, ok := thresholdKey(secret)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	defer zero(shared)
+
+	message, ok = secretbox.OpenWithAD(tb.sbox, tb.ad, shared)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+	return message, nil
+}
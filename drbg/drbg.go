@@ -0,0 +1,301 @@
+/*
+Package drbg implements the HMAC_DRBG deterministic random bit
+generator from NIST SP 800-90A, using HMAC-SHA-256 or HMAC-SHA-512.
+
+A DRBG is an io.Reader, and may be used as the PRNG of any of the box
+packages, or as the source of random data for a Sealer. Given the
+same entropy input, nonce and personalization string, a DRBG always
+produces the same output, which is useful for generating reproducible
+test vectors. For that use, the DRBG should be instantiated with
+New from fixed inputs. For production use, NewFromSource seeds it
+from a source of entropy, which is also used to reseed it when
+required and, if prediction resistance is requested, before every
+request.
+
+Both hash functions provide 256-bit security strength.
+*/
+package drbg
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"sync"
+)
+
+const (
+	// MinEntropySize is the smallest entropy input accepted, which
+	// is the security strength of the DRBG.
+	MinEntropySize = 32
+
+	// MinNonceSize is the smallest nonce accepted, half the
+	// security strength.
+	MinNonceSize = 16
+
+	// MaxInputSize is the largest entropy input, nonce,
+	// personalization string or additional input accepted. It
+	// exceeds the largest int on 32-bit platforms, so lengths are
+	// compared with it as uint64.
+	MaxInputSize = 1 << 32
+
+	// MaxBytesPerRequest is the largest amount of output a single
+	// call to Generate may request.
+	MaxBytesPerRequest = 1 << 16
+
+	// ReseedInterval is the number of requests that may be made
+	// before the DRBG must be reseeded.
+	ReseedInterval = 1 << 48
+)
+
+var (
+	// ErrReseedRequired is returned by Generate once ReseedInterval
+	// requests have been made, if the DRBG has no source of entropy
+	// to reseed itself from.
+	ErrReseedRequired = errors.New("drbg: reseed required")
+
+	// ErrInvalidInput is returned for requests or inputs that are
+	// too large.
+	ErrInvalidInput = errors.New("drbg: invalid input")
+
+	// ErrEntropy is returned when the source of entropy fails.
+	ErrEntropy = errors.New("drbg: failed to read entropy")
+
+	// ErrUninstantiated is returned by a DRBG that has been
+	// uninstantiated.
+	ErrUninstantiated = errors.New("drbg: uninstantiated")
+)
+
+// Options configures a DRBG. The zero value uses HMAC-SHA-256 with no
+// personalization string, no source of entropy and no prediction
+// resistance.
+type Options struct {
+	// Hash is the hash function used with HMAC, which should be
+	// sha256.New or sha512.New. It defaults to sha256.New.
+	Hash func() hash.Hash
+
+	// Personalization is an optional personalization string.
+	Personalization []byte
+
+	// Entropy is a source of entropy used to reseed the DRBG when
+	// ReseedInterval requests have been made. NewFromSource also
+	// uses it to seed the DRBG, and defaults it to crypto/rand.
+	Entropy io.Reader
+
+	// PredictionResistance reseeds the DRBG from Entropy before
+	// every request. It requires a source of entropy.
+	PredictionResistance bool
+}
+
+// A DRBG is an HMAC_DRBG instance. It is safe for concurrent use.
+type DRBG struct {
+	mu      sync.Mutex
+	h       func() hash.Hash
+	k, v    []byte
+	counter uint64
+	entropy io.Reader
+	pr      bool
+}
+
+// New instantiates a DRBG from the given entropy input and nonce,
+// and returns it with a boolean indicating success. The entropy input
+// must be at least MinEntropySize bytes and the nonce at least
+// MinNonceSize bytes. opts may be nil.
+func New(entropy, nonce []byte, opts *Options) (*DRBG, bool) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	h := opts.Hash
+	if h == nil {
+		h = sha256.New
+	}
+
+	if h().Size() < MinEntropySize {
+		return nil, false
+	} else if len(entropy) < MinEntropySize || uint64(len(entropy)) > MaxInputSize {
+		return nil, false
+	} else if len(nonce) < MinNonceSize || uint64(len(nonce)) > MaxInputSize {
+		return nil, false
+	} else if uint64(len(opts.Personalization)) > MaxInputSize {
+		return nil, false
+	} else if opts.PredictionResistance && opts.Entropy == nil {
+		return nil, false
+	}
+
+	d := &DRBG{
+		h:       h,
+		k:       make([]byte, h().Size()),
+		v:       make([]byte, h().Size()),
+		entropy: opts.Entropy,
+		pr:      opts.PredictionResistance,
+	}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+
+	d.update(entropy, nonce, opts.Personalization)
+	d.counter = 1
+	return d, true
+}
+
+// NewFromSource instantiates a DRBG with entropy input and a nonce
+// read from opts.Entropy, which defaults to crypto/rand.
+func NewFromSource(opts *Options) (*DRBG, bool) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Entropy == nil {
+		o.Entropy = rand.Reader
+	}
+
+	seed := make([]byte, MinEntropySize+MinNonceSize)
+	defer zero(seed)
+	if _, err := io.ReadFull(o.Entropy, seed); err != nil {
+		return nil, false
+	}
+	return New(seed[:MinEntropySize], seed[MinEntropySize:], &o)
+}
+
+func (d *DRBG) mac(key []byte, data ...[]byte) []byte {
+	m := hmac.New(d.h, key)
+	for _, p := range data {
+		m.Write(p)
+	}
+	return m.Sum(nil)
+}
+
+// update is the HMAC_DRBG_Update function; the provided data is the
+// concatenation of data.
+func (d *DRBG) update(data ...[]byte) {
+	empty := true
+	for _, p := range data {
+		if len(p) > 0 {
+			empty = false
+		}
+	}
+
+	in := append([][]byte{d.v, {0x00}}, data...)
+	d.k = d.mac(d.k, in...)
+	d.v = d.mac(d.k, d.v)
+	if empty {
+		return
+	}
+
+	in = append([][]byte{d.v, {0x01}}, data...)
+	d.k = d.mac(d.k, in...)
+	d.v = d.mac(d.k, d.v)
+}
+
+// Reseed reseeds the DRBG with the given entropy input, which must be
+// at least MinEntropySize bytes, and optional additional input.
+func (d *DRBG) Reseed(entropy, additional []byte) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.k == nil {
+		return false
+	}
+	return d.reseed(entropy, additional) == nil
+}
+
+func (d *DRBG) reseed(entropy, additional []byte) error {
+	if len(entropy) < MinEntropySize || uint64(len(entropy)) > MaxInputSize {
+		return ErrInvalidInput
+	} else if uint64(len(additional)) > MaxInputSize {
+		return ErrInvalidInput
+	}
+
+	d.update(entropy, additional)
+	d.counter = 1
+	return nil
+}
+
+func (d *DRBG) reseedFromSource(additional []byte) error {
+	entropy := make([]byte, MinEntropySize)
+	defer zero(entropy)
+	if _, err := io.ReadFull(d.entropy, entropy); err != nil {
+		return ErrEntropy
+	}
+	return d.reseed(entropy, additional)
+}
+
+// Generate fills out with random data, mixing in the optional
+// additional input. At most MaxBytesPerRequest bytes may be requested
+// at once.
+func (d *DRBG) Generate(out, additional []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.generate(out, additional)
+}
+
+func (d *DRBG) generate(out, additional []byte) error {
+	if d.k == nil {
+		return ErrUninstantiated
+	} else if len(out) > MaxBytesPerRequest || uint64(len(additional)) > MaxInputSize {
+		return ErrInvalidInput
+	}
+
+	if d.pr || d.counter > ReseedInterval {
+		if d.entropy == nil {
+			return ErrReseedRequired
+		}
+		if err := d.reseedFromSource(additional); err != nil {
+			return err
+		}
+		additional = nil
+	}
+
+	if len(additional) > 0 {
+		d.update(additional)
+	}
+
+	for n := 0; n < len(out); {
+		d.v = d.mac(d.k, d.v)
+		n += copy(out[n:], d.v)
+	}
+
+	d.update(additional)
+	d.counter++
+	return nil
+}
+
+// Read fills p with random data, making as many requests as needed.
+// It allows a DRBG to be used as an io.Reader.
+func (d *DRBG) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		chunk := p[n:]
+		if len(chunk) > MaxBytesPerRequest {
+			chunk = chunk[:MaxBytesPerRequest]
+		}
+		if err := d.generate(chunk, nil); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+// Uninstantiate wipes the DRBG's internal state. Any further use of
+// the DRBG fails.
+func (d *DRBG) Uninstantiate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	zero(d.k)
+	zero(d.v)
+	d.k, d.v = nil, nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,299 @@
+package drbg
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/kisom/aescrypt/secretbox"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("drbg: invalid test vector: %v", err)
+	}
+	return b
+}
+
+// The first vectors from the NIST CAVP HMAC_DRBG test file
+// (HMAC_DRBG.rsp) for SHA-256 and SHA-512, without prediction
+// resistance, personalization string or additional input. The DRBG
+// is instantiated, Generate is called twice, and the output of the
+// second call is returned.
+func TestCAVPVectors(t *testing.T) {
+	vectors := []struct {
+		opts     *Options
+		entropy  string
+		nonce    string
+		returned string
+	}{
+		{
+			opts:     nil,
+			entropy:  "ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488",
+			nonce:    "659ba96c601dc69fc902940805ec0ca8",
+			returned: "e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc107694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8",
+		},
+		{
+			opts:     &Options{Hash: sha512.New},
+			entropy:  "35049f389a33c0ecb1293238fd951f8ffd517dfde06041d32945b3e26914ba15",
+			nonce:    "f7328760be6168e6aa9fb54784989a11",
+			returned: "e76491b0260aacfded01ad39fbf1a66a88284caa5123368a2ad9330ee48335e3c9c9ba90e6cbc9429962d60c1a6661edcfaa31d972b8264b9d4562cf18494128a092c17a8da6f3113e8a7edfcd4427082bd390675e9662408144971717303d8dc352c9e8b95e7f35fa2ac9f549b292bc7c4bc7f01ee0a577859ef6e82d79ef23892d167c140d22aac32b64ccdfeee2730528a38763b24227f91ac3ffe47fb11538e435307e77481802b0f613f370ffb0dbeab774fe1efbb1a80d01154a9459e73ad361108bbc86b0914f095136cbe634555ce0bb263618dc5c367291ce0825518987154fe9ecb052b3f0a256fcc30cc14572531c9628973639beda456f2bddf6",
+		},
+	}
+
+	for i, v := range vectors {
+		d, ok := New(unhex(t, v.entropy), unhex(t, v.nonce), v.opts)
+		if !ok {
+			t.Fatalf("drbg: failed to instantiate vector %d", i)
+		}
+
+		expected := unhex(t, v.returned)
+		out := make([]byte, len(expected))
+		if err := d.Generate(out, nil); err != nil {
+			t.Fatalf("drbg: generate failed on vector %d: %v", i, err)
+		} else if err = d.Generate(out, nil); err != nil {
+			t.Fatalf("drbg: generate failed on vector %d: %v", i, err)
+		} else if !bytes.Equal(out, expected) {
+			t.Fatalf("drbg: vector %d failed", i)
+		}
+	}
+}
+
+// Known answers for prediction resistance and for reseeding with
+// additional input, laid out as in the CAVP HMAC_DRBG test files and
+// run with the CAVP procedure for SHA-256 with 1024 returned bits.
+// The inputs are SHA-256 hashes of fixed labels, and the returned
+// bits were computed with an independent implementation of SP
+// 800-90A, not taken from the CAVP files.
+func TestCAVPProcedure(t *testing.T) {
+	var (
+		entropy         = "f24e5711448518256c4ca6a175134dd9bfce98ad63372f23584c7c111394e0e6"
+		nonce           = "7e47af158e9db54fc5e4902996f5360a"
+		personalization = "4969925db1a21c8268eb46ad517786f9c0e388f44bb3944dc31bae9a6d695906"
+		additional1     = "ece1edcbb527b0f5b53645cc797d5c62b182f959f33b607336b0f8286e0f8004"
+		additional2     = "1ce2d3dcb1d96aacf4c876e3b2f50b7379df8dddab597a7db7153e1783a61a86"
+	)
+
+	// PredictionResistance = True: each request reseeds from the
+	// next EntropyInputPR, mixing in the request's additional input.
+	entropyPR1 := "a84d3df3ea05cef030cfbc84d3764de1f77ccffbc3fb5b5b9d72f71a0afa2233"
+	entropyPR2 := "162735c48b6b394411e06b3656b65aaeda08c9dcaafe0f9551a596b0d1aa40e5"
+	returned := "f1e1dc328c82f2f0f5eac9342bd9b6c333a7ce0c43a9ea0b839de48d47127c4c65d2ec42ef63c33b247f671b32bea7a2839788cbc7dc7677883289b4a94f3c8641e460f0d78b22655af280f6dbc57c22bbb1c8bcb71a0178c08eae8f2edd69a73feaa8bf60075c5c8b6e45faad6575dc736b40624f41997053127150017b0cb6"
+
+	source := bytes.NewReader(append(unhex(t, entropyPR1), unhex(t, entropyPR2)...))
+	d, ok := New(unhex(t, entropy), unhex(t, nonce), &Options{
+		Personalization:      unhex(t, personalization),
+		Entropy:              source,
+		PredictionResistance: true,
+	})
+	if !ok {
+		t.Fatal("drbg: failed to instantiate with prediction resistance")
+	}
+
+	out := make([]byte, 128)
+	if err := d.Generate(out, unhex(t, additional1)); err != nil {
+		t.Fatalf("drbg: generate failed: %v", err)
+	} else if err = d.Generate(out, unhex(t, additional2)); err != nil {
+		t.Fatalf("drbg: generate failed: %v", err)
+	} else if !bytes.Equal(out, unhex(t, returned)) {
+		t.Fatal("drbg: prediction resistance vector failed")
+	}
+
+	// PredictionResistance = False, with an explicit reseed carrying
+	// EntropyInputReseed and AdditionalInputReseed.
+	entropyReseed := "54b23a77a7e445a219b5fe307c347f86a7007b8651eecade7b3297737d07465e"
+	additionalReseed := "930e636477d79fb4f3844c56d3ccc9dde4092949e9535b19c4c76324bb1c587f"
+	returned = "fffd2051909153ee3b81fddee340377a888b01a87f5e0ffb5a75cf9431da0d2430f381145b60cdc6b8e7db8a21c9008b709a9a57b609a897ac5a376a0a40969ff06fd114f0b2487a3837ae1b7898a7a49198494a691cfa978ffeffd6b439f39459c53133a441b8e0cff0e382cf9090fb81a20a5149909e0a2f9d88d6fead8b85"
+
+	d, ok = New(unhex(t, entropy), unhex(t, nonce), &Options{
+		Personalization: unhex(t, personalization),
+	})
+	if !ok {
+		t.Fatal("drbg: failed to instantiate")
+	} else if !d.Reseed(unhex(t, entropyReseed), unhex(t, additionalReseed)) {
+		t.Fatal("drbg: reseed failed")
+	}
+
+	if err := d.Generate(out, unhex(t, additional1)); err != nil {
+		t.Fatalf("drbg: generate failed: %v", err)
+	} else if err = d.Generate(out, unhex(t, additional2)); err != nil {
+		t.Fatalf("drbg: generate failed: %v", err)
+	} else if !bytes.Equal(out, unhex(t, returned)) {
+		t.Fatal("drbg: reseed vector failed")
+	}
+}
+
+var (
+	testEntropy = []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17,
+		0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+	testNonce = []byte{
+		0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27,
+		0x28, 0x29, 0x2a, 0x2b, 0x2c, 0x2d, 0x2e, 0x2f,
+	}
+)
+
+func reseedEntropy(start byte) []byte {
+	entropy := make([]byte, MinEntropySize)
+	for i := range entropy {
+		entropy[i] = start + byte(i)
+	}
+	return entropy
+}
+
+// Known answers for reseeding, additional input and prediction
+// resistance, computed with an independent implementation of SP
+// 800-90A.
+func TestReseed(t *testing.T) {
+	opts := &Options{Personalization: []byte("cryptobox")}
+	d, ok := New(testEntropy, testNonce, opts)
+	if !ok {
+		t.Fatal("drbg: failed to instantiate")
+	}
+
+	out := make([]byte, 64)
+	if err := d.Generate(out, []byte("additional")); err != nil {
+		t.Fatalf("drbg: generate failed: %v", err)
+	} else if !bytes.Equal(out, unhex(t, "e212eaadca70d833c2e4c2eaf014ccff63aa9ca87ce7a35173ba7b796c5c57eafd6c99063a7260bc3da69292c01e16d7acf66f078ba07c09b5cd8f3c2532bd69")) {
+		t.Fatal("drbg: output with additional input is invalid")
+	}
+
+	if !d.Reseed(reseedEntropy(0x80), []byte("reseed")) {
+		t.Fatal("drbg: reseed failed")
+	} else if err := d.Generate(out, nil); err != nil {
+		t.Fatalf("drbg: generate failed: %v", err)
+	} else if !bytes.Equal(out, unhex(t, "38664cf6d5beebfe3a9f0dd59a097803816fa194b527332bff8f2f55d480c78305e4570f702021f6f6298153bc7e59ae7b7428716b956ad3e23c927d512995a7")) {
+		t.Fatal("drbg: output after reseeding is invalid")
+	}
+
+	if d.Reseed(reseedEntropy(0x80)[1:], nil) {
+		t.Fatal("drbg: reseeded with too little entropy")
+	}
+}
+
+func TestPredictionResistance(t *testing.T) {
+	source := bytes.NewReader(append(reseedEntropy(0x80), reseedEntropy(0xa0)...))
+	opts := &Options{
+		Personalization:      []byte("cryptobox"),
+		Entropy:              source,
+		PredictionResistance: true,
+	}
+	d, ok := New(testEntropy, testNonce, opts)
+	if !ok {
+		t.Fatal("drbg: failed to instantiate")
+	}
+
+	out := make([]byte, 64)
+	if err := d.Generate(out, []byte("additional")); err != nil {
+		t.Fatalf("drbg: generate failed: %v", err)
+	} else if !bytes.Equal(out, unhex(t, "1ff5374f62d3d43ac35f8a49c0a94d93c11772379997f19805ee19847413ba005ec8877f3718f3964b1626ddcc2b382d7e06e58c20a0c76e261b2675f1daa7d0")) {
+		t.Fatal("drbg: first output with prediction resistance is invalid")
+	}
+
+	if _, err := d.Read(out); err != nil {
+		t.Fatalf("drbg: read failed: %v", err)
+	} else if !bytes.Equal(out, unhex(t, "78caefc73db71697ce0bb8f063a76a72cb03f11ad732773c111cf7d33a502db414832f8c3d7b80ffcfc22c9080e67ede20401e79c6e78163907e03e61479cc2a")) {
+		t.Fatal("drbg: second output with prediction resistance is invalid")
+	}
+
+	// The source of entropy is exhausted.
+	if err := d.Generate(out, nil); !errors.Is(err, ErrEntropy) {
+		t.Fatalf("drbg: expected ErrEntropy, got %v", err)
+	}
+
+	opts.Entropy = nil
+	if _, ok = New(testEntropy, testNonce, opts); ok {
+		t.Fatal("drbg: prediction resistance without a source of entropy")
+	}
+}
+
+func TestReseedRequired(t *testing.T) {
+	d, ok := New(testEntropy, testNonce, nil)
+	if !ok {
+		t.Fatal("drbg: failed to instantiate")
+	}
+
+	out := make([]byte, 32)
+	d.counter = ReseedInterval + 1
+	if err := d.Generate(out, nil); !errors.Is(err, ErrReseedRequired) {
+		t.Fatalf("drbg: expected ErrReseedRequired, got %v", err)
+	} else if !d.Reseed(reseedEntropy(0x80), nil) {
+		t.Fatal("drbg: reseed failed")
+	} else if err = d.Generate(out, nil); err != nil {
+		t.Fatalf("drbg: generate failed after reseeding: %v", err)
+	}
+
+	d, ok = NewFromSource(&Options{Entropy: bytes.NewReader(make([]byte, 128))})
+	if !ok {
+		t.Fatal("drbg: failed to instantiate from source")
+	}
+	d.counter = ReseedInterval + 1
+	if err := d.Generate(out, nil); err != nil {
+		t.Fatalf("drbg: DRBG with a source of entropy should reseed itself: %v", err)
+	} else if d.counter != 2 {
+		t.Fatal("drbg: DRBG did not reseed itself")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if _, ok := New(testEntropy[1:], testNonce, nil); ok {
+		t.Fatal("drbg: instantiated with too little entropy")
+	} else if _, ok = New(testEntropy, testNonce[1:], nil); ok {
+		t.Fatal("drbg: instantiated with a short nonce")
+	}
+
+	d, ok := New(testEntropy, testNonce, nil)
+	if !ok {
+		t.Fatal("drbg: failed to instantiate")
+	}
+
+	if err := d.Generate(make([]byte, MaxBytesPerRequest+1), nil); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("drbg: expected ErrInvalidInput, got %v", err)
+	}
+
+	// Read splits large requests.
+	big := make([]byte, 3*MaxBytesPerRequest+1)
+	if n, err := d.Read(big); err != nil || n != len(big) {
+		t.Fatalf("drbg: read failed: %v", err)
+	}
+
+	d.Uninstantiate()
+	if _, err := d.Read(big); !errors.Is(err, ErrUninstantiated) {
+		t.Fatalf("drbg: expected ErrUninstantiated, got %v", err)
+	} else if d.Reseed(reseedEntropy(0x80), nil) {
+		t.Fatal("drbg: reseeded an uninstantiated DRBG")
+	}
+}
+
+// Two DRBGs instantiated with the same inputs produce identical
+// boxes, which can be used as golden test vectors.
+func TestDeterministicBoxes(t *testing.T) {
+	var boxes [][]byte
+	for i := 0; i < 2; i++ {
+		d, ok := New(testEntropy, testNonce, nil)
+		if !ok {
+			t.Fatal("drbg: failed to instantiate")
+		}
+
+		s := secretbox.NewSealer(&secretbox.Options{Rand: d})
+		key, ok := s.GenerateKey()
+		if !ok {
+			t.Fatal("drbg: failed to generate key")
+		}
+		box, ok := s.Seal([]byte("golden"), key)
+		if !ok {
+			t.Fatal("drbg: failed to seal message")
+		}
+		boxes = append(boxes, box)
+	}
+
+	if !bytes.Equal(boxes[0], boxes[1]) {
+		t.Fatal("drbg: boxes from identical DRBGs differ")
+	}
+}
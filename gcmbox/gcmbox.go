@@ -0,0 +1,235 @@
+/*
+gcmbox is used to authenticate and secure small messages. It
+provides the same interface as secretbox, but uses AES-128 in GCM
+mode for securing messages. On hardware with AES and carry-less
+multiplication instructions, this is considerably faster than
+secretbox's separate CTR and HMAC passes.
+
+Messages should be secured using the Seal function, and recovered
+using the Open function. A box (or authenticated and encrypted
+message) will be Overhead bytes longer than the message it
+came from; this package will not obscure the length of the
+message. Keys, if they are not generated using the GenerateKey
+function, should be KeySize bytes long. The KeyIsSuitable function
+may be used to test a key is the proper length.
+
+Each box uses a random 96-bit nonce. Following NIST SP 800-38D,
+a key must not be used to seal more than MaxMessages boxes. The
+Seal function keeps no state between calls and cannot enforce this
+limit; callers that seal many messages under the same key should use
+the Sealer type, which does.
+
+The boxes used in this package are suitable for 20-year security.
+*/
+package gcmbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"io"
+	"sync/atomic"
+
+	"github.com/kisom/aescrypt/health"
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+const VersionString = "1.0.0"
+
+// KeySize is the number of bytes a valid key should be.
+const KeySize = 16
+
+const (
+	nonceSize = 12
+	tagSize   = 16
+)
+
+// Overhead is the number of bytes of overhead when boxing a message.
+const Overhead = nonceSize + tagSize
+
+// MaxMessages is the number of boxes that may be sealed under a single
+// key with random nonces, as set by NIST SP 800-38D section 8.3.
+const MaxMessages = 1 << 32
+
+// MaxMessageSize is the largest message that may be sealed in a
+// single box.
+const MaxMessageSize = (1<<32 - 2) * aes.BlockSize
+
+// The default source for random data is the crypto/rand package's
+// Reader, wrapped in a health.Reader so that a failing source is
+// detected rather than causing keys and IVs to be reused.
+var PRNG io.Reader = health.NewReader(rand.Reader)
+
+type Key []byte
+
+// GenerateKey returns a key suitable for sealing and opening boxes, and
+// a boolean indicating success. If the boolean returns false, the Key
+// value must be discarded.
+func GenerateKey() (Key, bool) {
+	var key Key = make([]byte, KeySize)
+
+	_, err := io.ReadFull(PRNG, key)
+	return key, err == nil
+}
+
+func newAEAD(key Key) (cipher.AEAD, bool) {
+	if selftest.Failed() {
+		return nil, false
+	} else if !KeyIsSuitable(key) {
+		return nil, false
+	}
+
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, false
+	}
+
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		return nil, false
+	}
+	return aead, true
+}
+
+func seal(aead cipher.AEAD, r io.Reader, message, ad []byte) (box []byte, ok bool) {
+	if uint64(len(message)) > MaxMessageSize {
+		return nil, false
+	}
+
+	box = make([]byte, nonceSize, len(message)+Overhead)
+	if _, err := io.ReadFull(r, box); err != nil {
+		return nil, false
+	}
+	return aead.Seal(box, box, message, ad), true
+}
+
+func open(aead cipher.AEAD, box, ad []byte) (message []byte, ok bool) {
+	if len(box) < Overhead {
+		return nil, false
+	}
+
+	message, err := aead.Open(nil, box[:nonceSize], box[nonceSize:], ad)
+	if err != nil {
+		return nil, false
+	}
+	return message, true
+}
+
+// Seal returns an authenticated and encrypted message, and a boolean
+// indicating whether the sealing operation was successful. If it returns
+// true, the message was successfully sealed. The box will be Overhead
+// bytes longer than the message. Seal does not count the boxes sealed
+// under a key, and so does not enforce MaxMessages; callers that reuse
+// a key must count their boxes or use a Sealer.
+func Seal(message []byte, key Key) (box []byte, ok bool) {
+	aead, ok := newAEAD(key)
+	if !ok {
+		return nil, false
+	}
+	return seal(aead, PRNG, message, nil)
+}
+
+// SealWithAD seals a message as Seal does, additionally
+// authenticating the associated data ad. The associated data is not
+// stored in the box; the same data must be passed to OpenWithAD to
+// open it. A box sealed with empty associated data is identical to
+// one produced by Seal.
+func SealWithAD(message, ad []byte, key Key) (box []byte, ok bool) {
+	aead, ok := newAEAD(key)
+	if !ok {
+		return nil, false
+	}
+	return seal(aead, PRNG, message, ad)
+}
+
+// Open authenticates and decrypts a sealed message, also returning
+// whether the message was successfully opened. If this is false, the
+// message must be discarded. The returned message will be Overhead
+// bytes shorter than the box.
+func Open(box []byte, key Key) (message []byte, ok bool) {
+	aead, ok := newAEAD(key)
+	if !ok {
+		return nil, false
+	}
+	return open(aead, box, nil)
+}
+
+// OpenWithAD authenticates and decrypts a box sealed with
+// SealWithAD. It will fail if ad is not the associated data the box
+// was sealed with.
+func OpenWithAD(box, ad []byte, key Key) (message []byte, ok bool) {
+	aead, ok := newAEAD(key)
+	if !ok {
+		return nil, false
+	}
+	return open(aead, box, ad)
+}
+
+// KeyIsSuitable returns true if the byte slice represents a valid
+// gcmbox key.
+func KeyIsSuitable(key []byte) bool {
+	return subtle.ConstantTimeEq(int32(len(key)), int32(KeySize)) == 1
+}
+
+// Options configures a Sealer.
+type Options struct {
+	// Rand is the source of random data for nonces. If it is nil,
+	// the package-level PRNG is used.
+	Rand io.Reader
+}
+
+// A Sealer seals and opens boxes under a single key, counting the
+// boxes it seals. Once MaxMessages boxes have been sealed, it refuses
+// to seal any more, and the key must be replaced. A Sealer is safe for
+// concurrent use if its source of random data is.
+type Sealer struct {
+	aead  cipher.AEAD
+	rand  io.Reader
+	count atomic.Uint64
+}
+
+// NewSealer returns a Sealer for the key configured by opts, which may
+// be nil, and a boolean indicating success.
+func NewSealer(key Key, opts *Options) (*Sealer, bool) {
+	aead, ok := newAEAD(key)
+	if !ok {
+		return nil, false
+	}
+
+	s := &Sealer{aead: aead}
+	if opts != nil {
+		s.rand = opts.Rand
+	}
+	return s, true
+}
+
+func (s *Sealer) reader() io.Reader {
+	if s.rand == nil {
+		return PRNG
+	}
+	return s.rand
+}
+
+// Seal seals a message as the package-level Seal does. It returns
+// false once the key has sealed MaxMessages boxes.
+func (s *Sealer) Seal(message []byte) (box []byte, ok bool) {
+	if s.count.Add(1) > MaxMessages {
+		return nil, false
+	}
+	return seal(s.aead, s.reader(), message, nil)
+}
+
+// Open opens a box sealed under the Sealer's key.
+func (s *Sealer) Open(box []byte) (message []byte, ok bool) {
+	return open(s.aead, box, nil)
+}
+
+// Messages returns the number of boxes the Sealer has sealed.
+func (s *Sealer) Messages() uint64 {
+	n := s.count.Load()
+	if n > MaxMessages {
+		n = MaxMessages
+	}
+	return n
+}
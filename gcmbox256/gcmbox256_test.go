@@ -0,0 +1,263 @@
+package gcmbox256
+
+import "bytes"
+import "crypto/rand"
+import "fmt"
+import "io/ioutil"
+import "math/big"
+import "testing"
+
+var testMessages = []string{
+	"Hello, world.",
+	"Yes... yes. This is a fertile land, and we will thrive. We will rule over all this land, and we will call it... This Land.",
+	"Ah! Curse your sudden but inevitable betrayal!",
+	"And I'm thinkin' you weren't burdened with an overabundance of schooling. So why don't we just ignore each other until we go away?",
+	"Sir, I think you have a problem with your brain being missing.",
+	"It's the way of life in my findings that journeys end when and where they want to; and that's where you make your home.",
+	"I get confused. I remember everything. I remember too much. And... some of it's made up, and... some of it can't be quantified, and... there's secrets... and...",
+	"Yeah, we're pretty much just giving each other significant glances and laughing incessantly.",
+	"Jayne, go play with your rainstick.",
+}
+
+var (
+	testBoxes   = make([]string, len(testMessages))
+	testBoxFile []byte
+	testGoodKey = Key{
+		0x67, 0xfc, 0x79, 0x46, 0xd6, 0xbf, 0xdc, 0xde,
+		0x0c, 0xe3, 0x21, 0xea, 0xda, 0x02, 0xf9, 0xe5,
+		0x18, 0xb2, 0x3a, 0xd9, 0xe8, 0xa3, 0x3b, 0x20,
+		0x0f, 0xda, 0x96, 0xe6, 0x91, 0x78, 0x91, 0x1f,
+	}
+	testBadKey = Key{
+		0xe2, 0xbb, 0x58, 0x48, 0xba, 0x2a, 0x0c, 0xd0,
+		0x07, 0x3d, 0x32, 0xdb, 0x3a, 0xeb, 0x1b, 0x5b,
+		0x36, 0x0f, 0xd0, 0x8f, 0x1a, 0xa0, 0x77, 0x93,
+		0x7d, 0x0d, 0xd6, 0x38, 0x57, 0xe6, 0x80, 0xcb,
+	}
+)
+
+func randInt(max int64) int64 {
+	maxBig := big.NewInt(max)
+	n, err := rand.Int(PRNG, maxBig)
+	if err != nil {
+		return -1
+	}
+	return n.Int64()
+}
+
+func mutate(in []byte) (out []byte) {
+	out = make([]byte, len(in))
+	copy(out, in)
+
+	iterations := (randInt(int64(len(out))) / 2) + 1
+	if iterations == -1 {
+		panic("mutate failed")
+	}
+	for i := 0; i < int(iterations); i++ {
+		mByte := randInt(int64(len(out)))
+		mBit := randInt(7)
+		if mBit == -1 || mByte == -1 {
+			panic("mutate failed")
+		}
+		out[mByte] ^= (1 << uint(mBit))
+	}
+	if bytes.Equal(out, in) {
+		panic("mutate failed")
+	}
+	return out
+}
+
+// TestBoxing ensures that sealing a message into a box works properly.
+func TestBoxing(t *testing.T) {
+	for i := 0; i < len(testMessages); i++ {
+		box, ok := Seal([]byte(testMessages[i]), testGoodKey)
+		if !ok {
+			fmt.Println("Boxing failed: message", i)
+			t.FailNow()
+		} else if len(box) != len(testMessages[i])+Overhead {
+			fmt.Println("The box length is invalid.")
+			t.FailNow()
+		}
+		testBoxes[i] = string(box)
+	}
+}
+
+// TestUnboxing ensures that unsealing (or opening) a box to retrieve
+// a message works properly.
+func TestUnboxing(t *testing.T) {
+	for i := 0; i < len(testMessages); i++ {
+		message, ok := Open([]byte(testBoxes[i]), testGoodKey)
+		if !ok {
+			fmt.Println("Unboxing failed: message", i)
+			t.FailNow()
+		} else if string(message) != testMessages[i] {
+			fmt.Printf("Unboxing failed: expected '%s', got '%s'\n",
+				testMessages[i], string(message))
+			t.FailNow()
+		}
+	}
+}
+
+// TestUnboxingFails ensures that attempting to retrieve a message from
+// a box with the wrong key will fail.
+func TestUnboxingFails(t *testing.T) {
+	for i := 0; i < len(testMessages); i++ {
+		_, ok := Open([]byte(testBoxes[i]), testBadKey)
+		if ok {
+			fmt.Println("Unboxing should have failed with bad key:", i)
+			t.FailNow()
+		}
+		_, ok = Open(mutate([]byte(testBoxes[i])), testGoodKey)
+		if ok {
+			fmt.Println("Modified message should have failed:", i)
+			t.FailNow()
+		}
+	}
+}
+
+// TestEmptyBox validates the behaviour of an empty box.
+func TestEmptyBox(t *testing.T) {
+	var msg = []byte{}
+
+	box, ok := Seal(msg, testGoodKey)
+	if !ok {
+		t.Fatal("gcmbox256: failed to seal message")
+	}
+
+	out, ok := Open(box, testGoodKey)
+	if !ok {
+		t.Fatal("gcmbox256: failed to open message")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("gcmbox256: output message doesn't match original")
+	}
+
+	if _, ok = Open(box[1:], testGoodKey); ok {
+		t.Fatal("gcmbox256: truncated box opened")
+	}
+}
+
+// TestLargerBox tests the encryption of a 4,026 byte test file.
+func TestLargerBox(t *testing.T) {
+	var err error
+	testBoxFile, err = ioutil.ReadFile("testdata/TEST.txt")
+	if err != nil {
+		fmt.Println("Failed to read test data:", err.Error())
+		t.FailNow()
+	}
+
+	box, ok := Seal(testBoxFile, testGoodKey)
+	if !ok {
+		fmt.Println("Failed to box message.")
+		t.FailNow()
+	}
+
+	message, ok := Open(box, testGoodKey)
+	if !ok {
+		fmt.Println("Failed to unbox message.")
+		t.FailNow()
+	}
+
+	if !bytes.Equal(message, testBoxFile) {
+		fmt.Println("Recovered message is invalid.")
+		t.FailNow()
+	}
+}
+
+// TestAssociatedData ensures that a box sealed with associated data
+// only opens with the same associated data.
+func TestAssociatedData(t *testing.T) {
+	msg := []byte(testMessages[0])
+	ad := []byte("row 42")
+
+	box, ok := SealWithAD(msg, ad, testGoodKey)
+	if !ok {
+		t.Fatal("gcmbox256: failed to seal message with associated data")
+	} else if len(box) != len(msg)+Overhead {
+		t.Fatal("gcmbox256: the box length is invalid")
+	}
+
+	out, ok := OpenWithAD(box, ad, testGoodKey)
+	if !ok {
+		t.Fatal("gcmbox256: failed to open message with associated data")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("gcmbox256: output message doesn't match original")
+	}
+
+	if _, ok = OpenWithAD(box, []byte("row 43"), testGoodKey); ok {
+		t.Fatal("gcmbox256: box opened with the wrong associated data")
+	} else if _, ok = Open(box, testGoodKey); ok {
+		t.Fatal("gcmbox256: box with associated data opened with Open")
+	}
+}
+
+func TestKeyGeneration(t *testing.T) {
+	key, ok := GenerateKey()
+	if !ok {
+		t.Fatal("gcmbox256: failed to generate key")
+	} else if !KeyIsSuitable(key) {
+		t.Fatal("gcmbox256: generated key is not suitable")
+	} else if KeyIsSuitable(key[1:]) {
+		t.Fatal("gcmbox256: short key is suitable")
+	}
+}
+
+// TestSealerLimit ensures a Sealer stops sealing once the key has
+// reached its usage limit.
+func TestSealerLimit(t *testing.T) {
+	s, ok := NewSealer(testGoodKey, nil)
+	if !ok {
+		t.Fatal("gcmbox256: failed to create sealer")
+	}
+
+	box, ok := s.Seal([]byte(testMessages[0]))
+	if !ok {
+		t.Fatal("gcmbox256: sealer failed to seal message")
+	} else if s.Messages() != 1 {
+		t.Fatalf("gcmbox256: expected 1 message, have %d", s.Messages())
+	}
+
+	out, ok := Open(box, testGoodKey)
+	if !ok || string(out) != testMessages[0] {
+		t.Fatal("gcmbox256: failed to open sealer's box")
+	}
+
+	s.count.Store(MaxMessages - 1)
+	if _, ok = s.Seal([]byte(testMessages[0])); !ok {
+		t.Fatal("gcmbox256: sealer refused to seal its last message")
+	} else if _, ok = s.Seal([]byte(testMessages[0])); ok {
+		t.Fatal("gcmbox256: sealer sealed past its limit")
+	} else if s.Messages() != MaxMessages {
+		t.Fatalf("gcmbox256: expected %d messages, have %d", uint64(MaxMessages), s.Messages())
+	}
+
+	if out, ok = s.Open(box); !ok || string(out) != testMessages[0] {
+		t.Fatal("gcmbox256: worn out sealer failed to open box")
+	}
+}
+
+// Benchmark the Seal function, which secures the message.
+func BenchmarkSeal(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, ok := Seal(testBoxFile, testGoodKey)
+		if !ok {
+			fmt.Println("Couldn't seal message: benchmark aborted.")
+			b.FailNow()
+		}
+	}
+}
+
+// Benchmark the Open function, which retrieves a message from a box.
+func BenchmarkOpen(b *testing.B) {
+	box, ok := Seal(testBoxFile, testGoodKey)
+	if !ok {
+		fmt.Println("Can't seal message: benchmark aborted.")
+		b.FailNow()
+	}
+	for i := 0; i < b.N; i++ {
+		_, ok := Open(box, testGoodKey)
+		if !ok {
+			fmt.Println("Couldn't open message: benchmark aborted.")
+			b.FailNow()
+		}
+	}
+}
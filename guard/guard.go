@@ -0,0 +1,135 @@
+/*
+Package guard provides a container for key material that is kept out
+of the Go heap.
+
+The keys used by the box packages are plain byte slices. The garbage
+collector may copy them, the operating system may swap them to disk,
+and formatting one with %v prints it in full. A Key holds its contents
+in memory allocated with mmap and locked with mlock, so that it is
+neither moved nor swapped. Destroy wipes and releases the memory; a
+finalizer does the same for keys that are dropped without being
+destroyed, but callers should not rely on it. A Key never prints its
+contents: String and Format always return a redacted placeholder.
+
+Memory is only locked on Linux and Darwin, where the syscall package
+provides mmap and mlock. On other platforms, a Key is held in ordinary
+memory and only the wiping and formatting guarantees apply.
+*/
+package guard
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+const redacted = "guard.Key(REDACTED)"
+
+// A Key holds key material in locked memory. The zero value is not
+// usable; keys should be created with New, Random or FromBytes.
+type Key struct {
+	mu  sync.Mutex
+	mem []byte
+	key []byte
+}
+
+// New returns a zeroed key of the given size, and a boolean
+// indicating success.
+func New(size int) (*Key, bool) {
+	if size <= 0 {
+		return nil, false
+	}
+
+	mem, ok := alloc(size)
+	if !ok {
+		return nil, false
+	}
+
+	k := &Key{mem: mem, key: mem[:size:size]}
+	runtime.SetFinalizer(k, (*Key).Destroy)
+	return k, true
+}
+
+// Random returns a key of the given size filled with data read from
+// r, and a boolean indicating success.
+func Random(r io.Reader, size int) (*Key, bool) {
+	k, ok := New(size)
+	if !ok {
+		return nil, false
+	}
+
+	if _, err := io.ReadFull(r, k.key); err != nil {
+		k.Destroy()
+		return nil, false
+	}
+	return k, true
+}
+
+// FromBytes copies b into a new key and wipes b.
+func FromBytes(b []byte) (*Key, bool) {
+	k, ok := New(len(b))
+	if !ok {
+		return nil, false
+	}
+
+	copy(k.key, b)
+	Zero(b)
+	return k, true
+}
+
+// Bytes returns the key material. The returned slice refers to the
+// locked memory and must not be retained after the key is destroyed.
+// It returns nil if the key has been destroyed.
+func (k *Key) Bytes() []byte {
+	if k == nil {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.key
+}
+
+// Len returns the length of the key, or 0 if it has been destroyed.
+func (k *Key) Len() int {
+	return len(k.Bytes())
+}
+
+// Destroy wipes the key and releases its memory. It is safe to call
+// Destroy more than once, but the key must not be used while Destroy
+// is running.
+func (k *Key) Destroy() {
+	if k == nil {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.mem == nil {
+		return
+	}
+
+	Zero(k.mem)
+	free(k.mem)
+	k.mem, k.key = nil, nil
+	runtime.SetFinalizer(k, nil)
+}
+
+// String returns a placeholder that does not contain the key.
+func (k *Key) String() string {
+	return redacted
+}
+
+// Format implements fmt.Formatter so that no verb, including %x and
+// %#v, prints the key.
+func (k *Key) Format(f fmt.State, verb rune) {
+	io.WriteString(f, redacted)
+}
+
+// Zero wipes b.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin
+
+package guard
+
+import (
+	"os"
+	"syscall"
+)
+
+// alloc maps enough anonymous pages to hold size bytes and locks
+// them into memory.
+func alloc(size int) ([]byte, bool) {
+	pageSize := os.Getpagesize()
+	length := ((size + pageSize - 1) / pageSize) * pageSize
+
+	mem, err := syscall.Mmap(-1, 0, length, syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, false
+	}
+
+	if err = syscall.Mlock(mem); err != nil {
+		syscall.Munmap(mem)
+		return nil, false
+	}
+	return mem, true
+}
+
+func free(mem []byte) {
+	syscall.Munlock(mem)
+	syscall.Munmap(mem)
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package guard
+
+// alloc falls back to the Go heap on platforms where the syscall
+// package does not provide mmap and mlock.
+func alloc(size int) ([]byte, bool) {
+	return make([]byte, size), true
+}
+
+func free(mem []byte) {}
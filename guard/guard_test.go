@@ -0,0 +1,79 @@
+package guard
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	b := []byte("YELLOW SUBMARINE")
+	expected := append([]byte{}, b...)
+
+	k, ok := FromBytes(b)
+	if !ok {
+		t.Fatal("guard: failed to create key")
+	} else if !bytes.Equal(k.Bytes(), expected) {
+		t.Fatal("guard: key does not hold the original bytes")
+	} else if !bytes.Equal(b, make([]byte, len(b))) {
+		t.Fatal("guard: the original bytes were not wiped")
+	} else if k.Len() != len(expected) {
+		t.Fatal("guard: invalid key length")
+	}
+
+	k.Destroy()
+	if k.Bytes() != nil || k.Len() != 0 {
+		t.Fatal("guard: destroyed key still has contents")
+	}
+	k.Destroy()
+
+	if _, ok = New(0); ok {
+		t.Fatal("guard: created an empty key")
+	}
+}
+
+func TestZero(t *testing.T) {
+	b := []byte("YELLOW SUBMARINE")
+	Zero(b)
+	if !bytes.Equal(b, make([]byte, len(b))) {
+		t.Fatal("guard: Zero did not wipe its input")
+	}
+}
+
+func TestRandom(t *testing.T) {
+	k, ok := Random(rand.Reader, 32)
+	if !ok {
+		t.Fatal("guard: failed to generate key")
+	}
+	defer k.Destroy()
+
+	if bytes.Equal(k.Bytes(), make([]byte, 32)) {
+		t.Fatal("guard: random key is all zeroes")
+	}
+
+	if _, ok = Random(bytes.NewReader(make([]byte, 31)), 32); ok {
+		t.Fatal("guard: generated a key from a short read")
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	b := []byte("YELLOW SUBMARINE")
+	k, ok := FromBytes(append([]byte{}, b...))
+	if !ok {
+		t.Fatal("guard: failed to create key")
+	}
+	defer k.Destroy()
+
+	wrapped := struct{ K *Key }{k}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%x", "%X", "%q", "%d"} {
+		for _, out := range []string{fmt.Sprintf(format, k), fmt.Sprintf(format, wrapped)} {
+			if strings.Contains(out, string(b)) || strings.Contains(strings.ToLower(out), fmt.Sprintf("%x", b[:4])) {
+				t.Fatalf("guard: %s printed the key: %s", format, out)
+			} else if !strings.Contains(out, "REDACTED") {
+				t.Fatalf("guard: %s did not redact the key: %s", format, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cryptobox
+
+import (
+	"github.com/kisom/aescrypt/gcmbox"
+	"github.com/kisom/aescrypt/gcmbox256"
+	"github.com/kisom/aescrypt/secretbox"
+	"github.com/kisom/aescrypt/strongbox"
+)
+
+// The boxes produced by the symmetric packages carry nothing that
+// identifies the algorithm that sealed them. Seal prepends a header
+// to a box, made up of a magic byte, the format version and a suite
+// identifier, so that Open can dispatch to the right package.
+const (
+	Magic         byte = 0xcb
+	FormatVersion byte = 1
+	HeaderSize         = 3
+)
+
+// A Suite identifies the package used to seal a box.
+type Suite byte
+
+const (
+	SuiteSecretbox Suite = 1
+	SuiteStrongbox Suite = 2
+	SuiteGCMBox    Suite = 3
+	SuiteGCMBox256 Suite = 4
+)
+
+func header(suite Suite) []byte {
+	return []byte{Magic, FormatVersion, byte(suite)}
+}
+
+// Seal seals a message using the package identified by suite, and
+// prepends a header identifying it. The key must be suitable for that
+// package. The header is authenticated as associated data.
+func Seal(message []byte, key []byte, suite Suite) (box []byte, ok bool) {
+	hdr := header(suite)
+
+	var sbox []byte
+	switch suite {
+	case SuiteSecretbox:
+		sbox, ok = secretbox.SealWithAD(message, hdr, key)
+	case SuiteStrongbox:
+		sbox, ok = strongbox.SealWithAD(message, hdr, key)
+	case SuiteGCMBox:
+		sbox, ok = gcmbox.SealWithAD(message, hdr, key)
+	case SuiteGCMBox256:
+		sbox, ok = gcmbox256.SealWithAD(message, hdr, key)
+	default:
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	return append(hdr, sbox...), true
+}
+
+// BoxSuite returns the suite recorded in a box's header. It returns
+// false if the box does not have a valid header.
+func BoxSuite(box []byte) (Suite, bool) {
+	if len(box) < HeaderSize {
+		return 0, false
+	} else if box[0] != Magic || box[1] != FormatVersion {
+		return 0, false
+	}
+
+	suite := Suite(box[2])
+	switch suite {
+	case SuiteSecretbox, SuiteStrongbox, SuiteGCMBox, SuiteGCMBox256:
+		return suite, true
+	default:
+		return 0, false
+	}
+}
+
+func openSuite(suite Suite, box []byte, key []byte) (message []byte, ok bool) {
+	hdr, sbox := box[:HeaderSize], box[HeaderSize:]
+	switch suite {
+	case SuiteSecretbox:
+		return secretbox.OpenWithAD(sbox, hdr, key)
+	case SuiteStrongbox:
+		return strongbox.OpenWithAD(sbox, hdr, key)
+	case SuiteGCMBox:
+		return gcmbox.OpenWithAD(sbox, hdr, key)
+	case SuiteGCMBox256:
+		return gcmbox256.OpenWithAD(sbox, hdr, key)
+	default:
+		return nil, false
+	}
+}
+
+// Open opens a box produced by Seal, using the package named in its
+// header. Boxes without a header are rejected; OpenLegacy also accepts
+// them.
+func Open(box []byte, key []byte) (message []byte, ok bool) {
+	suite, ok := BoxSuite(box)
+	if !ok {
+		return nil, false
+	}
+	return openSuite(suite, box, key)
+}
+
+// OpenLegacy opens a box as Open does, but also accepts legacy boxes
+// without a header: these were sealed by either secretbox or
+// strongbox, which is determined from the size of the key. It should
+// only be used where boxes sealed before headers were introduced may
+// still be encountered, as it gives up the header's guarantee that a
+// box is opened by the package that sealed it.
+func OpenLegacy(box []byte, key []byte) (message []byte, ok bool) {
+	// A legacy box begins with a random IV, which may happen to look
+	// like a header, so a box with a header that fails to open is
+	// also tried as a legacy box.
+	if suite, ok := BoxSuite(box); ok {
+		if message, ok = openSuite(suite, box, key); ok {
+			return message, true
+		}
+	}
+
+	switch len(key) {
+	case secretbox.KeySize:
+		return secretbox.Open(box, key)
+	case strongbox.KeySize:
+		return strongbox.Open(box, key)
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,141 @@
+package cryptobox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/kisom/aescrypt/gcmbox"
+	"github.com/kisom/aescrypt/gcmbox256"
+	"github.com/kisom/aescrypt/secretbox"
+	"github.com/kisom/aescrypt/strongbox"
+)
+
+var testMessage = []byte("Ah! Curse your sudden but inevitable betrayal!")
+
+func testKeys(t *testing.T) map[Suite][]byte {
+	sk, ok := secretbox.GenerateKey()
+	if !ok {
+		t.Fatal("cryptobox: failed to generate secretbox key")
+	}
+	stk, ok := strongbox.GenerateKey()
+	if !ok {
+		t.Fatal("cryptobox: failed to generate strongbox key")
+	}
+	gk, ok := gcmbox.GenerateKey()
+	if !ok {
+		t.Fatal("cryptobox: failed to generate gcmbox key")
+	}
+	g256k, ok := gcmbox256.GenerateKey()
+	if !ok {
+		t.Fatal("cryptobox: failed to generate gcmbox256 key")
+	}
+
+	return map[Suite][]byte{
+		SuiteSecretbox: sk,
+		SuiteStrongbox: stk,
+		SuiteGCMBox:    gk,
+		SuiteGCMBox256: g256k,
+	}
+}
+
+func TestHeaderBoxes(t *testing.T) {
+	keys := testKeys(t)
+	for suite, key := range keys {
+		box, ok := Seal(testMessage, key, suite)
+		if !ok {
+			t.Fatalf("cryptobox: failed to seal suite %d", suite)
+		} else if s, ok := BoxSuite(box); !ok || s != suite {
+			t.Fatalf("cryptobox: box header does not record suite %d", suite)
+		}
+
+		out, ok := Open(box, key)
+		if !ok {
+			t.Fatalf("cryptobox: failed to open suite %d", suite)
+		} else if !bytes.Equal(out, testMessage) {
+			t.Fatalf("cryptobox: suite %d did not round trip", suite)
+		}
+
+		// Changing the suite must not allow the box to be opened.
+		for other := range keys {
+			if other == suite {
+				continue
+			}
+			bad := append([]byte{}, box...)
+			bad[2] = byte(other)
+			if _, ok = Open(bad, key); ok {
+				t.Fatalf("cryptobox: suite %d box opened as suite %d", suite, other)
+			}
+			if _, ok = Open(box, keys[other]); ok {
+				t.Fatalf("cryptobox: suite %d box opened with suite %d key", suite, other)
+			}
+		}
+	}
+
+	// The header is authenticated for every suite, so a box will not
+	// open without it.
+	box, ok := Seal(testMessage, keys[SuiteGCMBox], SuiteGCMBox)
+	if !ok {
+		t.Fatal("cryptobox: failed to seal gcmbox")
+	} else if _, ok = gcmbox.Open(box[HeaderSize:], keys[SuiteGCMBox]); ok {
+		t.Fatal("cryptobox: gcmbox opened without its header")
+	}
+
+	if _, ok = Seal(testMessage, keys[SuiteSecretbox], Suite(0)); ok {
+		t.Fatal("cryptobox: sealed with an unknown suite")
+	} else if _, ok = Seal(testMessage, keys[SuiteStrongbox], SuiteSecretbox); ok {
+		t.Fatal("cryptobox: sealed with the wrong key size")
+	}
+}
+
+func TestLegacyBoxes(t *testing.T) {
+	keys := testKeys(t)
+
+	sbox, ok := secretbox.Seal(testMessage, keys[SuiteSecretbox])
+	if !ok {
+		t.Fatal("cryptobox: failed to seal secretbox")
+	}
+	stbox, ok := strongbox.Seal(testMessage, keys[SuiteStrongbox])
+	if !ok {
+		t.Fatal("cryptobox: failed to seal strongbox")
+	}
+
+	if out, ok := OpenLegacy(sbox, keys[SuiteSecretbox]); !ok || !bytes.Equal(out, testMessage) {
+		t.Fatal("cryptobox: failed to open legacy secretbox")
+	} else if out, ok = OpenLegacy(stbox, keys[SuiteStrongbox]); !ok || !bytes.Equal(out, testMessage) {
+		t.Fatal("cryptobox: failed to open legacy strongbox")
+	} else if _, ok = OpenLegacy(sbox, keys[SuiteStrongbox]); ok {
+		t.Fatal("cryptobox: legacy secretbox opened with a strongbox key")
+	}
+
+	// Legacy boxes are only accepted when asked for.
+	if _, ok = Open(sbox, keys[SuiteSecretbox]); ok {
+		t.Fatal("cryptobox: Open accepted a legacy secretbox")
+	} else if _, ok = Open(stbox, keys[SuiteStrongbox]); ok {
+		t.Fatal("cryptobox: Open accepted a legacy strongbox")
+	}
+
+	// A box with a header also opens with OpenLegacy.
+	box, ok := Seal(testMessage, keys[SuiteGCMBox], SuiteGCMBox)
+	if !ok {
+		t.Fatal("cryptobox: failed to seal gcmbox")
+	} else if out, ok := OpenLegacy(box, keys[SuiteGCMBox]); !ok || !bytes.Equal(out, testMessage) {
+		t.Fatal("cryptobox: failed to open a box with a header with OpenLegacy")
+	}
+
+	// A legacy box whose IV happens to look like a header must still
+	// open.
+	defer func(r io.Reader) { secretbox.PRNG = r }(secretbox.PRNG)
+	secretbox.PRNG = io.MultiReader(bytes.NewReader(header(SuiteSecretbox)), rand.Reader)
+	sbox, ok = secretbox.Seal(testMessage, keys[SuiteSecretbox])
+	if !ok {
+		t.Fatal("cryptobox: failed to seal secretbox")
+	} else if _, ok = BoxSuite(sbox); !ok {
+		t.Fatal("cryptobox: legacy box should look like it has a header")
+	}
+
+	if out, ok := OpenLegacy(sbox, keys[SuiteSecretbox]); !ok || !bytes.Equal(out, testMessage) {
+		t.Fatal("cryptobox: failed to open legacy secretbox with header-like IV")
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Package health provides continuous health tests for a source of
+random data.
+
+A source that returns a stuck or repeating stream would cause the
+box packages to reuse keys and IVs. A Reader wraps a source and runs
+the health tests from NIST SP 800-90B section 4.4 on every byte it
+returns, treating each byte as a sample:
+
+  - the repetition count test fails if RCTCutoff identical bytes are
+    returned in a row;
+  - the adaptive proportion test fails if any byte value appears
+    APTCutoff or more times in a window of APTWindow bytes;
+  - the output is also split into BlockSize byte blocks, and the test
+    fails if two consecutive blocks are equal.
+
+The cutoffs assume the source provides full entropy and give a false
+positive rate of 2^-40 per sample. Before it returns any data, a
+Reader runs the tests over StartupSamples bytes, which are discarded.
+
+A test can fail on good data, so data that fails a test is wiped and
+discarded, and the Reader reads fresh data from the source and tests
+it from scratch. Only after FailureLimit consecutive failures does a
+Reader fail closed: every later read returns ErrHealthTest until it
+is Reset.
+
+A Reader serialises reads from its source and the tests behind a
+mutex, so goroutines sharing one Reader contend for it. Each of the
+box packages wraps its own Reader as its PRNG, and callers that need
+more throughput can give each goroutine its own Reader, for example
+as the random source of a Sealer.
+*/
+package health
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// RCTCutoff is the number of identical consecutive bytes that
+	// fails the repetition count test.
+	RCTCutoff = 6
+
+	// APTWindow is the window size for the adaptive proportion
+	// test, and APTCutoff the number of occurrences of the window's
+	// first byte that fails it.
+	APTWindow = 512
+	APTCutoff = 20
+
+	// BlockSize is the size of the blocks compared by the
+	// consecutive block test.
+	BlockSize = 16
+
+	// StartupSamples is the number of bytes tested and discarded
+	// before a Reader returns any data.
+	StartupSamples = 1024
+
+	// FailureLimit is the number of consecutive failed reads after
+	// which a Reader fails closed.
+	FailureLimit = 3
+)
+
+// ErrHealthTest is returned once the source has failed a health test.
+var ErrHealthTest = errors.New("health: random source failed health test")
+
+// A Reader runs health tests on the data read from a source. It is
+// safe for concurrent use.
+type Reader struct {
+	mu     sync.Mutex
+	source io.Reader
+	err    error
+	ready  bool
+
+	// failures counts consecutive failed reads.
+	failures int
+
+	// repetition count test
+	last    byte
+	repeats int
+
+	// adaptive proportion test
+	aptFirst byte
+	aptCount int
+	aptSeen  int
+
+	// consecutive block test
+	block    [BlockSize]byte
+	prev     [BlockSize]byte
+	blockLen int
+	havePrev bool
+}
+
+// NewReader returns a Reader that tests data read from source.
+func NewReader(source io.Reader) *Reader {
+	return &Reader{source: source}
+}
+
+// sample runs the tests on one byte, and returns false if any fail.
+func (r *Reader) sample(b byte) bool {
+	if r.repeats > 0 && b == r.last {
+		r.repeats++
+	} else {
+		r.repeats = 1
+	}
+	r.last = b
+	if r.repeats >= RCTCutoff {
+		return false
+	}
+
+	if r.aptSeen == 0 {
+		r.aptFirst = b
+		r.aptCount = 1
+	} else if b == r.aptFirst {
+		r.aptCount++
+	}
+	r.aptSeen++
+	if r.aptCount >= APTCutoff {
+		return false
+	} else if r.aptSeen == APTWindow {
+		r.aptSeen = 0
+	}
+
+	r.block[r.blockLen] = b
+	r.blockLen++
+	if r.blockLen == BlockSize {
+		r.blockLen = 0
+		if r.havePrev && subtle.ConstantTimeCompare(r.block[:], r.prev[:]) == 1 {
+			return false
+		}
+		r.prev = r.block
+		r.havePrev = true
+	}
+	return true
+}
+
+func (r *Reader) check(p []byte) bool {
+	for _, b := range p {
+		if !r.sample(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// reset clears the state of the tests, so that the next sample is
+// tested as if it were the first.
+func (r *Reader) reset() {
+	r.last, r.repeats = 0, 0
+	r.aptFirst, r.aptCount, r.aptSeen = 0, 0, 0
+	r.block = [BlockSize]byte{}
+	r.prev = [BlockSize]byte{}
+	r.blockLen = 0
+	r.havePrev = false
+}
+
+// fail wipes and discards data that failed a test. It returns
+// ErrHealthTest once FailureLimit consecutive reads have failed, and
+// nil if the caller should read fresh data and try again.
+func (r *Reader) fail(p []byte) error {
+	for i := range p {
+		p[i] = 0
+	}
+	r.reset()
+
+	r.failures++
+	if r.failures >= FailureLimit {
+		r.err = ErrHealthTest
+	}
+	return r.err
+}
+
+func (r *Reader) startup() error {
+	buf := make([]byte, StartupSamples)
+	defer func() {
+		for i := range buf {
+			buf[i] = 0
+		}
+	}()
+
+	for {
+		if _, err := io.ReadFull(r.source, buf); err != nil {
+			return err
+		} else if r.check(buf) {
+			break
+		} else if err = r.fail(buf); err != nil {
+			return err
+		}
+	}
+	r.failures = 0
+	r.ready = true
+	return nil
+}
+
+// Read reads from the source into p and tests the data. If any test
+// fails, p is wiped and refilled with fresh data from the source. Once
+// FailureLimit consecutive reads have failed, p is wiped and
+// ErrHealthTest is returned, as it will be for every later read until
+// the Reader is Reset.
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return 0, r.err
+	} else if !r.ready {
+		if err := r.startup(); err != nil {
+			return 0, err
+		}
+	}
+
+	for {
+		n, err := r.source.Read(p)
+		if r.check(p[:n]) {
+			r.failures = 0
+			return n, err
+		} else if ferr := r.fail(p[:n]); ferr != nil {
+			return 0, ferr
+		} else if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Reset clears a failure, and reruns the startup tests on the next
+// read. It should only be called once the source has been repaired.
+func (r *Reader) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reset()
+	r.failures = 0
+	r.err = nil
+	r.ready = false
+}
+
+// Err returns ErrHealthTest if the source has failed a health test,
+// and nil otherwise.
+func (r *Reader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
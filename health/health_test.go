@@ -0,0 +1,106 @@
+package health
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestGoodSource(t *testing.T) {
+	r := NewReader(rand.Reader)
+	buf := make([]byte, 1<<20)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("health: read from a good source failed: %v", err)
+	} else if r.Err() != nil {
+		t.Fatal("health: good source failed a health test")
+	}
+}
+
+// source returns StartupSamples random bytes to pass the startup
+// tests, followed by count copies of bad.
+func source(t *testing.T, bad []byte, count int) io.Reader {
+	good := make([]byte, StartupSamples)
+	if _, err := io.ReadFull(rand.Reader, good); err != nil {
+		t.Fatalf("health: failed to read random data: %v", err)
+	}
+	return io.MultiReader(bytes.NewReader(good),
+		bytes.NewReader(bytes.Repeat(bad, count)), rand.Reader)
+}
+
+func random(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		t.Fatalf("health: failed to read random data: %v", err)
+	}
+	return b
+}
+
+// expectFailure checks that a single failure is recovered from, and
+// that FailureLimit consecutive failures are not.
+func expectFailure(t *testing.T, name string, bad []byte) {
+	r := NewReader(source(t, bad, 1))
+	buf := make([]byte, len(bad))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("health: %s: reader did not recover from one failure: %v", name, err)
+	} else if r.Err() != nil {
+		t.Fatalf("health: %s: one failure should not be latched", name)
+	}
+
+	r = NewReader(source(t, bad, FailureLimit))
+	if _, err := io.ReadFull(r, buf); !errors.Is(err, ErrHealthTest) {
+		t.Fatalf("health: %s: expected ErrHealthTest, got %v", name, err)
+	} else if !bytes.Equal(buf, make([]byte, len(bad))) {
+		t.Fatalf("health: %s: failing data was returned", name)
+	}
+
+	// The reader fails closed.
+	if _, err := r.Read(buf); !errors.Is(err, ErrHealthTest) {
+		t.Fatalf("health: %s: reader recovered after a failure", name)
+	} else if r.Err() == nil {
+		t.Fatalf("health: %s: Err should report the failure", name)
+	}
+
+	// The source is good again after the bad data.
+	r.Reset()
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("health: %s: read after Reset failed: %v", name, err)
+	} else if r.Err() != nil {
+		t.Fatalf("health: %s: Err should be cleared by Reset", name)
+	}
+}
+
+func TestRepetitionCount(t *testing.T) {
+	bad := append(random(t, 100), bytes.Repeat([]byte{0x5a}, RCTCutoff)...)
+	expectFailure(t, "repetition count", bad)
+
+	// A stuck source fails during startup.
+	r := NewReader(bytes.NewReader(make([]byte, FailureLimit*StartupSamples)))
+	if _, err := r.Read(make([]byte, 16)); !errors.Is(err, ErrHealthTest) {
+		t.Fatalf("health: expected startup failure, got %v", err)
+	}
+}
+
+func TestAdaptiveProportion(t *testing.T) {
+	// Alternate two values, so that no value repeats but one makes
+	// up half of the window.
+	bad := make([]byte, APTWindow)
+	for i := range bad {
+		bad[i] = byte(i%2) * 0xff
+	}
+	expectFailure(t, "adaptive proportion", bad)
+}
+
+func TestConsecutiveBlocks(t *testing.T) {
+	block := random(t, BlockSize)
+	bad := append(append([]byte{}, block...), block...)
+	expectFailure(t, "consecutive blocks", bad)
+}
+
+func TestSourceError(t *testing.T) {
+	r := NewReader(bytes.NewReader(random(t, StartupSamples-1)))
+	if _, err := r.Read(make([]byte, 16)); err == nil {
+		t.Fatal("health: read from a short source should fail")
+	}
+}
@@ -0,0 +1,24 @@
+// Package selftest records whether a power-on self-test has failed.
+// The flag is shared by every package in cryptobox: once any
+// package's self-test fails, all of them refuse to seal or open
+// boxes.
+package selftest
+
+import "sync/atomic"
+
+var failed atomic.Bool
+
+// Fail records a self-test failure.
+func Fail() {
+	failed.Store(true)
+}
+
+// Failed returns true if a self-test has failed.
+func Failed() bool {
+	return failed.Load()
+}
+
+// Reset clears a recorded failure. It is only intended for tests.
+func Reset() {
+	failed.Store(false)
+}
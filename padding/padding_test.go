@@ -0,0 +1,104 @@
+package padding
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPADMESizes(t *testing.T) {
+	vectors := map[int]int{
+		0:    0,
+		1:    1,
+		9:    10,
+		100:  104,
+		1000: 1024,
+		1025: 1088,
+	}
+
+	for n, expected := range vectors {
+		if size := PADME.Size(n); size != expected {
+			t.Fatalf("padding: PADME(%d) should be %d, not %d", n, expected, size)
+		}
+	}
+
+	for n := 1; n < 1<<16; n++ {
+		if over := PADME.Size(n) - n; over*100 > 12*n {
+			t.Fatalf("padding: PADME overhead for %d bytes is %d", n, over)
+		}
+	}
+}
+
+func TestBucketSizes(t *testing.T) {
+	scheme := Buckets(64, 256, 1024)
+	vectors := map[int]int{
+		1:    64,
+		64:   64,
+		65:   256,
+		1024: 1024,
+		1025: 2048,
+		3000: 3072,
+	}
+
+	for n, expected := range vectors {
+		if size := scheme.Size(n); size != expected {
+			t.Fatalf("padding: bucket size for %d should be %d, not %d", n, expected, size)
+		}
+	}
+
+	if Overhead(63, scheme) != 1 || Overhead(64, scheme) != 192 {
+		t.Fatal("padding: bucket overhead is invalid")
+	}
+}
+
+func TestPadding(t *testing.T) {
+	schemes := []Scheme{PADME, Buckets(32, 128), Buckets()}
+	for _, scheme := range schemes {
+		for n := 0; n < 300; n++ {
+			msg := bytes.Repeat([]byte{0}, n)
+			padded := Pad(msg, scheme)
+			if len(padded) != n+Overhead(n, scheme) {
+				t.Fatalf("padding: padded length for %d bytes is invalid", n)
+			}
+
+			out, ok := Unpad(padded, scheme)
+			if !ok {
+				t.Fatalf("padding: failed to unpad %d bytes", n)
+			} else if !bytes.Equal(out, msg) {
+				t.Fatalf("padding: %d bytes did not round trip", n)
+			}
+		}
+	}
+}
+
+func TestBadPadding(t *testing.T) {
+	scheme := Buckets(32)
+	padded := Pad([]byte("hello"), scheme)
+
+	if _, ok := Unpad(padded[:len(padded)-1], scheme); ok {
+		t.Fatal("padding: unpadded a truncated message")
+	} else if _, ok = Unpad(append(padded, 0), scheme); ok {
+		t.Fatal("padding: unpadded an extended message")
+	} else if _, ok = Unpad(make([]byte, 32), scheme); ok {
+		t.Fatal("padding: unpadded a message without a marker")
+	} else if _, ok = Unpad(nil, scheme); ok {
+		t.Fatal("padding: unpadded an empty message")
+	}
+
+	padded[len(padded)-1] = 1
+	if _, ok := Unpad(padded, scheme); ok {
+		t.Fatal("padding: unpadded a message with invalid padding")
+	}
+}
+
+func TestBadBuckets(t *testing.T) {
+	for _, sizes := range [][]int{{0}, {-64}, {64, 32}, {64, 64}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("padding: accepted bucket sizes %v", sizes)
+				}
+			}()
+			Buckets(sizes...)
+		}()
+	}
+}
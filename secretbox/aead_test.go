@@ -0,0 +1,89 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestAEADMatchesBox checks that the AEAD produces the same
+// ciphertext as a box, so that the two may be used interchangeably.
+func TestAEADMatchesBox(t *testing.T) {
+	a, ok := NewAEAD(testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create AEAD")
+	} else if a.NonceSize() != Overhead-a.Overhead() {
+		t.Fatal("secretbox: AEAD nonce and overhead don't match Overhead")
+	}
+
+	ad := []byte("header")
+	for i := 0; i < len(testMessages); i++ {
+		msg := []byte(testMessages[i])
+		box, ok := SealWithAD(msg, ad, testGoodKey)
+		if !ok {
+			t.Fatal("secretbox: failed to seal message")
+		}
+
+		nonce := box[:a.NonceSize()]
+		out, err := a.Open(nil, nonce, box[a.NonceSize():], ad)
+		if err != nil {
+			t.Fatalf("secretbox: AEAD failed to open box: %v", err)
+		} else if !bytes.Equal(out, msg) {
+			t.Fatal("secretbox: AEAD output doesn't match original")
+		}
+
+		ct := a.Seal(nil, nonce, msg, ad)
+		if !bytes.Equal(ct, box[a.NonceSize():]) {
+			t.Fatal("secretbox: AEAD ciphertext doesn't match box")
+		}
+	}
+}
+
+func TestAEAD(t *testing.T) {
+	a, ok := NewAEAD(testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create AEAD")
+	}
+	bad, ok := NewAEAD(testBadKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create AEAD")
+	}
+
+	nonce := make([]byte, a.NonceSize())
+	msg := []byte(testMessages[1])
+	ad := []byte("header")
+
+	// Sealing in place must work.
+	buf := make([]byte, len(msg), len(msg)+a.Overhead())
+	copy(buf, msg)
+	ct := a.Seal(buf[:0], nonce, buf, ad)
+	if len(ct) != len(msg)+a.Overhead() {
+		t.Fatal("secretbox: AEAD ciphertext length is invalid")
+	}
+
+	prefix := []byte("prefix")
+	out, err := a.Open(prefix, nonce, ct, ad)
+	if err != nil {
+		t.Fatalf("secretbox: AEAD failed to open: %v", err)
+	} else if !bytes.Equal(out, append([]byte("prefix"), msg...)) {
+		t.Fatal("secretbox: AEAD output doesn't match original")
+	}
+
+	if _, err = a.Open(nil, nonce, ct, nil); err == nil {
+		t.Fatal("secretbox: AEAD opened with the wrong additional data")
+	} else if _, err = bad.Open(nil, nonce, ct, ad); err == nil {
+		t.Fatal("secretbox: AEAD opened with the wrong key")
+	} else if _, err = a.Open(nil, nonce, mutate(ct), ad); err == nil {
+		t.Fatal("secretbox: AEAD opened a modified ciphertext")
+	} else if _, err = a.Open(nil, nonce, ct[:a.Overhead()-1], ad); err == nil {
+		t.Fatal("secretbox: AEAD opened a truncated ciphertext")
+	}
+
+	nonce[0] ^= 1
+	if _, err = a.Open(nil, nonce, ct, ad); err == nil {
+		t.Fatal("secretbox: AEAD opened with the wrong nonce")
+	}
+
+	if _, ok = NewAEAD(testGoodKey[1:]); ok {
+		t.Fatal("secretbox: NewAEAD accepted a short key")
+	}
+}
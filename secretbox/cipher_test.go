@@ -0,0 +1,101 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCipher(t *testing.T) {
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create cipher")
+	}
+
+	prefix := []byte("prefix")
+	for i := 0; i < len(testMessages); i++ {
+		msg := []byte(testMessages[i])
+		dst := make([]byte, len(prefix), len(prefix)+len(msg)+Overhead)
+		copy(dst, prefix)
+
+		box, ok := c.SealTo(dst, msg)
+		if !ok {
+			t.Fatalf("secretbox: cipher failed to seal message %d", i)
+		} else if &box[0] != &dst[0] {
+			t.Fatal("secretbox: SealTo did not use the destination buffer")
+		} else if !bytes.Equal(box[:len(prefix)], prefix) {
+			t.Fatal("secretbox: SealTo overwrote the destination")
+		}
+
+		// Boxes from a Cipher and from Seal are interchangeable.
+		box = box[len(prefix):]
+		if out, ok := Open(box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+			t.Fatalf("secretbox: Open failed on cipher box %d", i)
+		}
+
+		sbox, ok := Seal(msg, testGoodKey)
+		if !ok {
+			t.Fatalf("secretbox: failed to seal message %d", i)
+		}
+		out, ok := c.OpenTo(prefix, sbox)
+		if !ok {
+			t.Fatalf("secretbox: cipher failed to open box %d", i)
+		} else if !bytes.Equal(out, append(append([]byte{}, prefix...), msg...)) {
+			t.Fatal("secretbox: OpenTo output doesn't match original")
+		}
+
+		if out, ok = OpenTo(nil, box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+			t.Fatalf("secretbox: OpenTo failed on box %d", i)
+		} else if _, ok = OpenTo(nil, box, testBadKey); ok {
+			t.Fatal("secretbox: OpenTo opened a box with the wrong key")
+		}
+
+		box[len(box)-1] ^= 1
+		if _, ok = c.OpenTo(nil, box); ok {
+			t.Fatal("secretbox: cipher opened a modified box")
+		}
+	}
+
+	if _, ok = c.OpenTo(nil, make([]byte, Overhead-1)); ok {
+		t.Fatal("secretbox: cipher opened a short box")
+	} else if _, ok = NewCipher(testGoodKey[1:]); ok {
+		t.Fatal("secretbox: created a cipher with an invalid key")
+	} else if _, ok = SealTo(nil, testBoxFile, testGoodKey[1:]); ok {
+		t.Fatal("secretbox: SealTo sealed with an invalid key")
+	}
+}
+
+// Benchmark sealing with a Cipher into a reused buffer.
+func BenchmarkCipherSeal(b *testing.B) {
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		b.Fatal("secretbox: failed to create cipher")
+	}
+	buf := make([]byte, 0, len(testBoxFile)+Overhead)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok = c.SealTo(buf, testBoxFile); !ok {
+			b.Fatal("secretbox: couldn't seal message")
+		}
+	}
+}
+
+// Benchmark opening with a Cipher into a reused buffer.
+func BenchmarkCipherOpen(b *testing.B) {
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		b.Fatal("secretbox: failed to create cipher")
+	}
+	box, ok := Seal(testBoxFile, testGoodKey)
+	if !ok {
+		b.Fatal("secretbox: couldn't seal message")
+	}
+	buf := make([]byte, 0, len(testBoxFile))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok = c.OpenTo(buf, box); !ok {
+			b.Fatal("secretbox: couldn't open message")
+		}
+	}
+}
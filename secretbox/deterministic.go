@@ -0,0 +1,77 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// DeterministicOverhead is the number of bytes of overhead when
+// sealing a message with SealDeterministic.
+const DeterministicOverhead = aes.BlockSize
+
+// sivLabel is used to derive the key for synthetic IVs. The IV is an
+// HMAC over the associated data and message, laid out as an AEAD tag
+// is; without its own key, it could be computed by sealing with the
+// AEAD, whose nonce is chosen by the caller.
+var sivLabel = []byte("secretbox deterministic box")
+
+// syntheticIV derives the CTR IV for a deterministic box from the
+// message and associated data.
+func syntheticIV(key Key, message, ad []byte) []byte {
+	skey := subkey(key[cryptKeySize:], sivLabel)
+	defer zero(skey)
+
+	tag := computeTag(skey, ad, message)
+	return tag[:aes.BlockSize]
+}
+
+// SealDeterministic seals a message such that the same message and
+// associated data sealed under the same key always produce the same
+// box. Rather than using a random IV, the IV is derived from an HMAC
+// over the message and associated data, in the style of AES-SIV; it
+// also serves as the authentication tag. The box will be
+// DeterministicOverhead bytes longer than the message.
+//
+// Deterministic boxes reveal when two messages are equal, and should
+// only be used where that is acceptable, such as deduplicated storage.
+// They must be opened with OpenDeterministic.
+func SealDeterministic(message, ad []byte, key Key) (box []byte, ok bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	}
+
+	c, err := newBlock(key[:cryptKeySize])
+	if err != nil {
+		return nil, false
+	}
+
+	iv := syntheticIV(key, message, ad)
+	box = make([]byte, aes.BlockSize+len(message))
+	copy(box, iv)
+	cipher.NewCTR(c, iv).XORKeyStream(box[aes.BlockSize:], message)
+	return box, true
+}
+
+// OpenDeterministic opens a box sealed with SealDeterministic,
+// verifying that its IV matches the recovered message and associated
+// data.
+func OpenDeterministic(box, ad []byte, key Key) (message []byte, ok bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	} else if len(box) < DeterministicOverhead {
+		return nil, false
+	}
+
+	message, err := decrypt(key[:cryptKeySize], box)
+	if err != nil {
+		return nil, false
+	}
+
+	iv := syntheticIV(key, message, ad)
+	if subtle.ConstantTimeCompare(iv, box[:aes.BlockSize]) != 1 {
+		zero(message)
+		return nil, false
+	}
+	return message, true
+}
@@ -0,0 +1,96 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeterministicBox(t *testing.T) {
+	ad := []byte("content store")
+	for i := 0; i < len(testMessages); i++ {
+		msg := []byte(testMessages[i])
+		box, ok := SealDeterministic(msg, ad, testGoodKey)
+		if !ok {
+			t.Fatal("secretbox: failed to seal deterministic box")
+		} else if len(box) != len(msg)+DeterministicOverhead {
+			t.Fatal("secretbox: the box length is invalid")
+		}
+
+		again, ok := SealDeterministic(msg, ad, testGoodKey)
+		if !ok {
+			t.Fatal("secretbox: failed to seal deterministic box")
+		} else if !bytes.Equal(box, again) {
+			t.Fatal("secretbox: deterministic boxes differ")
+		}
+
+		other, ok := SealDeterministic(msg, nil, testGoodKey)
+		if !ok {
+			t.Fatal("secretbox: failed to seal deterministic box")
+		} else if bytes.Equal(box, other) {
+			t.Fatal("secretbox: associated data did not change the box")
+		}
+
+		out, ok := OpenDeterministic(box, ad, testGoodKey)
+		if !ok {
+			t.Fatal("secretbox: failed to open deterministic box")
+		} else if !bytes.Equal(out, msg) {
+			t.Fatal("secretbox: output message doesn't match original")
+		}
+
+		if _, ok = OpenDeterministic(box, nil, testGoodKey); ok {
+			t.Fatal("secretbox: box opened with the wrong associated data")
+		} else if _, ok = OpenDeterministic(box, ad, testBadKey); ok {
+			t.Fatal("secretbox: box opened with the wrong key")
+		} else if _, ok = OpenDeterministic(mutate(box), ad, testGoodKey); ok {
+			t.Fatal("secretbox: modified box opened")
+		}
+	}
+
+	box, ok := SealDeterministic(nil, nil, testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to seal empty deterministic box")
+	}
+	if out, ok := OpenDeterministic(box, nil, testGoodKey); !ok || len(out) != 0 {
+		t.Fatal("secretbox: failed to open empty deterministic box")
+	}
+	if _, ok = OpenDeterministic(box[1:], nil, testGoodKey); ok {
+		t.Fatal("secretbox: truncated box opened")
+	}
+}
+
+// The synthetic IV is computed under its own key, so that the AEAD
+// can't be used to compute it.
+func TestSyntheticIVKey(t *testing.T) {
+	tagKey := testGoodKey[cryptKeySize:]
+	sivKey := subkey(tagKey, sivLabel)
+	if bytes.Equal(sivKey, tagKey) || bytes.Equal(sivKey, subkey(tagKey, adLabel)) {
+		t.Fatal("secretbox: synthetic IV key should differ from the tag keys")
+	}
+
+	a, ok := NewAEAD(testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create AEAD")
+	}
+
+	// Choose the nonce and plaintext so that the AEAD tags the same
+	// input as the synthetic IV covers.
+	ad := []byte("content store")
+	msg := []byte(testMessages[1])
+	nonce := msg[:a.NonceSize()]
+	stream := a.Seal(nil, nonce, make([]byte, len(msg)-len(nonce)), nil)
+	pt := make([]byte, len(msg)-len(nonce))
+	for i := range pt {
+		pt[i] = msg[len(nonce)+i] ^ stream[i]
+	}
+	sealed := a.Seal(nil, nonce, pt, ad)
+	if !bytes.Equal(sealed[:len(pt)], msg[len(nonce):]) {
+		t.Fatal("secretbox: failed to choose the AEAD ciphertext")
+	}
+
+	box, ok := SealDeterministic(msg, ad, testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to seal deterministic box")
+	} else if bytes.Equal(sealed[len(pt):len(pt)+DeterministicOverhead], box[:DeterministicOverhead]) {
+		t.Fatal("secretbox: the AEAD computed a synthetic IV")
+	}
+}
@@ -0,0 +1,16 @@
+package secretbox
+
+import "errors"
+
+// Errors returned by the error-returning variants of the package's
+// functions. They may be compared with errors.Is. ErrAuthFailed is
+// returned for any box that fails authentication: a box sealed under
+// a different key is deliberately indistinguishable from one that has
+// been modified.
+var (
+	ErrInvalidKey = errors.New("secretbox: invalid key")
+	ErrMalformed  = errors.New("secretbox: malformed box")
+	ErrAuthFailed = errors.New("secretbox: message authentication failed")
+	ErrRandomness = errors.New("secretbox: failed to read random data")
+	ErrSelfTest   = errors.New("secretbox: self-test failed")
+)
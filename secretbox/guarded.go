@@ -0,0 +1,27 @@
+package secretbox
+
+import (
+	"runtime"
+
+	"github.com/kisom/aescrypt/guard"
+)
+
+// GenerateGuardedKey returns a key suitable for sealing and opening
+// boxes, held in locked memory, and a boolean indicating success.
+// The key should be destroyed with its Destroy method when it is no
+// longer needed.
+func GenerateGuardedKey() (*guard.Key, bool) {
+	return defaultSealer.GenerateGuardedKey()
+}
+
+// SealGuarded seals a message as Seal does, using a guarded key.
+func SealGuarded(message []byte, key *guard.Key) (box []byte, ok bool) {
+	defer runtime.KeepAlive(key)
+	return Seal(message, key.Bytes())
+}
+
+// OpenGuarded opens a box as Open does, using a guarded key.
+func OpenGuarded(box []byte, key *guard.Key) (message []byte, ok bool) {
+	defer runtime.KeepAlive(key)
+	return Open(box, key.Bytes())
+}
@@ -0,0 +1,37 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGuardedBoxing(t *testing.T) {
+	key, ok := GenerateGuardedKey()
+	if !ok {
+		t.Fatal("secretbox: failed to generate guarded key")
+	}
+
+	msg := []byte(testMessages[0])
+	box, ok := SealGuarded(msg, key)
+	if !ok {
+		t.Fatal("secretbox: failed to seal with guarded key")
+	}
+
+	out, ok := OpenGuarded(box, key)
+	if !ok {
+		t.Fatal("secretbox: failed to open with guarded key")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: output message doesn't match original")
+	}
+
+	if out, ok = Open(box, key.Bytes()); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: guarded box should open with Open")
+	}
+
+	key.Destroy()
+	if _, ok = SealGuarded(msg, key); ok {
+		t.Fatal("secretbox: sealed with a destroyed key")
+	} else if _, ok = OpenGuarded(box, key); ok {
+		t.Fatal("secretbox: opened with a destroyed key")
+	}
+}
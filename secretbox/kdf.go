@@ -0,0 +1,43 @@
+package secretbox
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"hash"
+)
+
+func hkdfKey(h func() hash.Hash, secret, salt, info []byte, size int) ([]byte, bool) {
+	key, err := hkdf.Key(h, secret, salt, string(info), size)
+	if err != nil {
+		return nil, false
+	}
+	return key, true
+}
+
+// kdfInfo builds the HKDF info parameter for a label and context,
+// following the layout of the fixed input in SP 800-108: the label
+// and context separated by a zero byte.
+func kdfInfo(label, context []byte) []byte {
+	info := make([]byte, 0, len(label)+1+len(context))
+	info = append(info, label...)
+	info = append(info, 0)
+	return append(info, context...)
+}
+
+// DeriveKey derives a subkey from a master key using HKDF with
+// HMAC-SHA-256 (RFC 5869, NIST SP 800-56C). The label names the
+// purpose of the key, such as "sessions" or "exports", and must not be
+// empty or contain a zero byte; the context may bind the key to
+// further information, such as a tenant ID, and may be empty. Keys
+// derived with different labels or contexts are independent, and
+// none reveal the master key.
+func DeriveKey(master Key, context, label []byte) (Key, bool) {
+	if !KeyIsSuitable(master) {
+		return nil, false
+	} else if len(label) == 0 || bytes.IndexByte(label, 0) != -1 {
+		return nil, false
+	}
+
+	return hkdfKey(sha256.New, master, nil, kdfInfo(label, context), KeySize)
+}
@@ -0,0 +1,79 @@
+package secretbox
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("secretbox: invalid test vector: %v", err)
+	}
+	return b
+}
+
+// Test cases 1 and 3 from RFC 5869, appendix A.
+func TestHKDFVectors(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	salt := unhex(t, "000102030405060708090a0b0c")
+	info := unhex(t, "f0f1f2f3f4f5f6f7f8f9")
+
+	vectors := []struct {
+		salt, info []byte
+		okm        string
+	}{
+		{salt, info, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"},
+		{nil, nil, "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"},
+	}
+
+	for i, v := range vectors {
+		okm, ok := hkdfKey(sha256.New, ikm, v.salt, v.info, 42)
+		if !ok {
+			t.Fatalf("secretbox: HKDF failed on vector %d", i)
+		} else if !bytes.Equal(okm, unhex(t, v.okm)) {
+			t.Fatalf("secretbox: HKDF vector %d failed", i)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	master := make(Key, KeySize)
+	for i := range master {
+		master[i] = byte(i)
+	}
+
+	expected := unhex(t, "709e404a7fc3b947c89ac8cd62e5a41bcc0c50cf5ea52ff78424952cefde1af3c66a16c293188aa9209ace8ad44ea37b")
+	key, ok := DeriveKey(master, []byte("tenant-1"), []byte("sessions"))
+	if !ok {
+		t.Fatal("secretbox: failed to derive key")
+	} else if !bytes.Equal(key, expected) {
+		t.Fatal("secretbox: derived key does not match known answer")
+	} else if !KeyIsSuitable(key) {
+		t.Fatal("secretbox: derived key is not suitable")
+	}
+
+	other, ok := DeriveKey(master, []byte("tenant-1"), []byte("exports"))
+	if !ok {
+		t.Fatal("secretbox: failed to derive key")
+	} else if bytes.Equal(key, other) {
+		t.Fatal("secretbox: keys with different labels should differ")
+	}
+
+	other, ok = DeriveKey(master, []byte("tenant-2"), []byte("sessions"))
+	if !ok {
+		t.Fatal("secretbox: failed to derive key")
+	} else if bytes.Equal(key, other) {
+		t.Fatal("secretbox: keys with different contexts should differ")
+	}
+
+	if _, ok = DeriveKey(master[1:], nil, []byte("sessions")); ok {
+		t.Fatal("secretbox: derived a key from an invalid master key")
+	} else if _, ok = DeriveKey(master, nil, nil); ok {
+		t.Fatal("secretbox: derived a key with an empty label")
+	} else if _, ok = DeriveKey(master, nil, []byte("sessions\x00")); ok {
+		t.Fatal("secretbox: derived a key with a label containing a zero byte")
+	}
+}
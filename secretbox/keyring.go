@@ -0,0 +1,174 @@
+package secretbox
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// KeyID identifies a key in a Keyring. It is stored in front of every
+// box sealed by the keyring, and is authenticated as associated data.
+type KeyID uint32
+
+const keyIDSize = 4
+
+// KeyringOverhead is the number of bytes of overhead when boxing a
+// message with a Keyring.
+const KeyringOverhead = keyIDSize + Overhead
+
+// A Keyring holds a primary key, used to seal new boxes, and any
+// number of retired keys that are only used to open existing boxes.
+// Boxes sealed by a keyring record the ID of the key that sealed them,
+// so that opening a box does not require trying every key. A Keyring
+// is safe for concurrent use.
+type Keyring struct {
+	lock    sync.RWMutex
+	primary KeyID
+	keys    map[KeyID]Key
+}
+
+func copyKey(key Key) Key {
+	kcopy := make(Key, len(key))
+	copy(kcopy, key)
+	return kcopy
+}
+
+// NewKeyring returns a keyring using key as its primary key, and a
+// boolean indicating success.
+func NewKeyring(id KeyID, key Key) (*Keyring, bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	}
+
+	return &Keyring{
+		primary: id,
+		keys:    map[KeyID]Key{id: copyKey(key)},
+	}, true
+}
+
+// AddRetired adds a key that will only be used to open boxes. It
+// returns false if the key is invalid or the ID is already in use.
+func (kr *Keyring) AddRetired(id KeyID, key Key) bool {
+	if !KeyIsSuitable(key) {
+		return false
+	}
+
+	kr.lock.Lock()
+	defer kr.lock.Unlock()
+	if _, ok := kr.keys[id]; ok {
+		return false
+	}
+	kr.keys[id] = copyKey(key)
+	return true
+}
+
+// Rotate makes key the primary key; the previous primary key is
+// retired. It returns false if the key is invalid or the ID is
+// already in use.
+func (kr *Keyring) Rotate(id KeyID, key Key) bool {
+	if !KeyIsSuitable(key) {
+		return false
+	}
+
+	kr.lock.Lock()
+	defer kr.lock.Unlock()
+	if _, ok := kr.keys[id]; ok {
+		return false
+	}
+	kr.keys[id] = copyKey(key)
+	kr.primary = id
+	return true
+}
+
+// Remove zeroes and removes a retired key from the keyring. Boxes
+// sealed under it can no longer be opened. The primary key cannot be
+// removed.
+func (kr *Keyring) Remove(id KeyID) bool {
+	kr.lock.Lock()
+	defer kr.lock.Unlock()
+	key, ok := kr.keys[id]
+	if !ok || id == kr.primary {
+		return false
+	}
+	zero(key)
+	delete(kr.keys, id)
+	return true
+}
+
+// Primary returns the ID of the primary key.
+func (kr *Keyring) Primary() KeyID {
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	return kr.primary
+}
+
+func sealWithID(message []byte, id KeyID, key Key) (box []byte, ok bool) {
+	idBytes := make([]byte, keyIDSize)
+	binary.BigEndian.PutUint32(idBytes, uint32(id))
+
+	sbox, ok := SealWithAD(message, idBytes, key)
+	if !ok {
+		return nil, false
+	}
+	return append(idBytes, sbox...), true
+}
+
+// Seal seals a message under the primary key. The box will be
+// KeyringOverhead bytes longer than the message.
+func (kr *Keyring) Seal(message []byte) (box []byte, ok bool) {
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	return sealWithID(message, kr.primary, kr.keys[kr.primary])
+}
+
+// BoxKeyID returns the ID of the key that sealed a keyring box.
+func BoxKeyID(box []byte) (KeyID, bool) {
+	if len(box) < KeyringOverhead {
+		return 0, false
+	}
+	return KeyID(binary.BigEndian.Uint32(box)), true
+}
+
+// Open opens a box sealed by the keyring under either the primary key
+// or a retired key.
+func (kr *Keyring) Open(box []byte) (message []byte, ok bool) {
+	id, ok := BoxKeyID(box)
+	if !ok {
+		return nil, false
+	}
+
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	key, ok := kr.keys[id]
+	if !ok {
+		return nil, false
+	}
+	return OpenWithAD(box[keyIDSize:], box[:keyIDSize], key)
+}
+
+// Rewrap opens a box and seals its contents under the primary key, so
+// that stored boxes may be migrated away from a retired key. Boxes
+// already sealed under the primary key are returned unchanged.
+func (kr *Keyring) Rewrap(box []byte) (rewrapped []byte, ok bool) {
+	id, ok := BoxKeyID(box)
+	if !ok {
+		return nil, false
+	}
+
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	key, ok := kr.keys[id]
+	if !ok {
+		return nil, false
+	}
+
+	message, ok := OpenWithAD(box[keyIDSize:], box[:keyIDSize], key)
+	if !ok {
+		return nil, false
+	} else if id == kr.primary {
+		zero(message)
+		return box, true
+	}
+	defer zero(message)
+
+	return sealWithID(message, kr.primary, kr.keys[kr.primary])
+}
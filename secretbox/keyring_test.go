@@ -0,0 +1,91 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	kr, ok := NewKeyring(1, testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create keyring")
+	}
+
+	msg := []byte(testMessages[4])
+	oldBox, ok := kr.Seal(msg)
+	if !ok {
+		t.Fatal("secretbox: keyring failed to seal message")
+	} else if len(oldBox) != len(msg)+KeyringOverhead {
+		t.Fatal("secretbox: the box length is invalid")
+	} else if id, _ := BoxKeyID(oldBox); id != 1 {
+		t.Fatalf("secretbox: expected key ID 1, have %d", id)
+	}
+
+	if !kr.Rotate(2, testBadKey) {
+		t.Fatal("secretbox: failed to rotate keyring")
+	} else if kr.Primary() != 2 {
+		t.Fatal("secretbox: rotation did not change the primary key")
+	} else if kr.Rotate(1, testBadKey) {
+		t.Fatal("secretbox: rotated to an ID already in use")
+	}
+
+	newBox, ok := kr.Seal(msg)
+	if !ok {
+		t.Fatal("secretbox: keyring failed to seal message")
+	} else if id, _ := BoxKeyID(newBox); id != 2 {
+		t.Fatalf("secretbox: expected key ID 2, have %d", id)
+	}
+
+	for _, box := range [][]byte{oldBox, newBox} {
+		out, ok := kr.Open(box)
+		if !ok {
+			t.Fatal("secretbox: keyring failed to open box")
+		} else if !bytes.Equal(out, msg) {
+			t.Fatal("secretbox: output message doesn't match original")
+		}
+	}
+
+	// The key ID is authenticated, so a box can't be redirected to
+	// another key, and a box can't be opened with a plain Open.
+	bad := append([]byte{}, newBox...)
+	bad[keyIDSize-1] = 1
+	if _, ok = kr.Open(bad); ok {
+		t.Fatal("secretbox: box with modified key ID opened")
+	} else if _, ok = kr.Open(mutate(newBox)); ok {
+		t.Fatal("secretbox: modified box opened")
+	} else if _, ok = Open(oldBox[keyIDSize:], testGoodKey); ok {
+		t.Fatal("secretbox: keyring box opened without its key ID")
+	}
+
+	rewrapped, ok := kr.Rewrap(oldBox)
+	if !ok {
+		t.Fatal("secretbox: failed to rewrap box")
+	} else if id, _ := BoxKeyID(rewrapped); id != 2 {
+		t.Fatalf("secretbox: expected rewrapped key ID 2, have %d", id)
+	}
+
+	if kr.Remove(2) {
+		t.Fatal("secretbox: removed the primary key")
+	} else if !kr.Remove(1) {
+		t.Fatal("secretbox: failed to remove retired key")
+	} else if _, ok = kr.Open(oldBox); ok {
+		t.Fatal("secretbox: opened box sealed with a removed key")
+	}
+
+	out, ok := kr.Open(rewrapped)
+	if !ok {
+		t.Fatal("secretbox: keyring failed to open rewrapped box")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: output message doesn't match original")
+	}
+
+	if !kr.AddRetired(1, testGoodKey) {
+		t.Fatal("secretbox: failed to add retired key")
+	} else if kr.AddRetired(2, testGoodKey) {
+		t.Fatal("secretbox: added a retired key with an ID in use")
+	} else if kr.Primary() != 2 {
+		t.Fatal("secretbox: adding a retired key changed the primary key")
+	} else if _, ok = kr.Open(oldBox); !ok {
+		t.Fatal("secretbox: failed to open box with re-added key")
+	}
+}
@@ -0,0 +1,28 @@
+package secretbox
+
+import "github.com/kisom/aescrypt/padding"
+
+// PaddedOverhead returns the number of bytes of overhead when boxing
+// an n byte message with SealPadded using the scheme.
+func PaddedOverhead(n int, scheme padding.Scheme) int {
+	return Overhead + padding.Overhead(n, scheme)
+}
+
+// SealPadded pads the message according to the scheme before sealing
+// it, so that the box reveals only the padded length of the message.
+// The box must be opened with OpenPadded using the same scheme.
+func SealPadded(message []byte, key Key, scheme padding.Scheme) (box []byte, ok bool) {
+	padded := padding.Pad(message, scheme)
+	defer zero(padded)
+	return Seal(padded, key)
+}
+
+// OpenPadded opens a box sealed with SealPadded, and verifies and
+// removes the padding.
+func OpenPadded(box []byte, key Key, scheme padding.Scheme) (message []byte, ok bool) {
+	padded, ok := Open(box, key)
+	if !ok {
+		return nil, false
+	}
+	return padding.Unpad(padded, scheme)
+}
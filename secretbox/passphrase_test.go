@@ -0,0 +1,114 @@
+package secretbox
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+)
+
+var testPassphrase = []byte("correct horse battery staple")
+
+func TestPassphraseBox(t *testing.T) {
+	defer func(n int) { PassphraseIterations = n }(PassphraseIterations)
+	PassphraseIterations = minIterations + 1
+
+	msg := []byte(testMessages[2])
+	box, ok := SealWithPassphrase(msg, testPassphrase)
+	if !ok {
+		t.Fatal("secretbox: failed to seal with passphrase")
+	}
+
+	out, ok := OpenWithPassphrase(box, testPassphrase)
+	if !ok {
+		t.Fatal("secretbox: failed to open with passphrase")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: output message doesn't match original")
+	}
+
+	if _, ok = OpenWithPassphrase(box, []byte("incorrect horse")); ok {
+		t.Fatal("secretbox: box opened with the wrong passphrase")
+	}
+
+	// Changing the header must cause the box to fail to open. The
+	// upper bytes of the iteration count are skipped, as changing
+	// them makes the key derivation very slow.
+	for i := 0; i < passphraseHeaderSize+saltSize; i++ {
+		if i >= 2 && i < 5 {
+			continue
+		}
+		bad := append([]byte{}, box...)
+		bad[i] ^= 1
+		if _, ok = OpenWithPassphrase(bad, testPassphrase); ok {
+			t.Fatalf("secretbox: box with modified header byte %d opened", i)
+		}
+	}
+}
+
+// TestPassphraseIterations ensures boxes sealed with an older iteration
+// count still open after PassphraseIterations is raised.
+func TestPassphraseIterations(t *testing.T) {
+	defer func(n int) { PassphraseIterations = n }(PassphraseIterations)
+
+	msg := []byte(testMessages[3])
+	PassphraseIterations = minIterations
+	box, ok := SealWithPassphrase(msg, testPassphrase)
+	if !ok {
+		t.Fatal("secretbox: failed to seal with passphrase")
+	}
+
+	PassphraseIterations = 2 * minIterations
+	out, ok := OpenWithPassphrase(box, testPassphrase)
+	if !ok {
+		t.Fatal("secretbox: failed to open older passphrase box")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: output message doesn't match original")
+	}
+
+	PassphraseIterations = minIterations - 1
+	if _, ok = SealWithPassphrase(msg, testPassphrase); ok {
+		t.Fatal("secretbox: sealed with too few iterations")
+	}
+
+	// A count that does not fit in the header is refused before any
+	// work is done. int cannot hold one on 32-bit platforms.
+	if strconv.IntSize > 32 {
+		n := uint64(math.MaxUint32)
+		PassphraseIterations = int(n + minIterations)
+		if _, ok = SealWithPassphrase(msg, testPassphrase); ok {
+			t.Fatal("secretbox: sealed with an iteration count too large for the header")
+		}
+	}
+
+	// A box may not demand much more work than PassphraseIterations.
+	PassphraseIterations = (maxIterationFactor + 1) * minIterations
+	box, ok = SealWithPassphrase(msg, testPassphrase)
+	if !ok {
+		t.Fatal("secretbox: failed to seal with passphrase")
+	}
+	PassphraseIterations = minIterations
+	if _, ok = OpenWithPassphrase(box, testPassphrase); ok {
+		t.Fatal("secretbox: opened a box with too many iterations")
+	}
+}
+
+func TestDeriveKeyFromPassphrase(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key, ok := DeriveKeyFromPassphrase(testPassphrase, salt, minIterations)
+	if !ok {
+		t.Fatal("secretbox: failed to derive key")
+	} else if !KeyIsSuitable(key) {
+		t.Fatal("secretbox: derived key is not suitable")
+	}
+
+	again, ok := DeriveKeyFromPassphrase(testPassphrase, salt, minIterations)
+	if !ok || !bytes.Equal(key, again) {
+		t.Fatal("secretbox: key derivation is not deterministic")
+	}
+
+	if _, ok = DeriveKeyFromPassphrase(testPassphrase, salt[:8], minIterations); ok {
+		t.Fatal("secretbox: derived key with a short salt")
+	} else if _, ok = DeriveKeyFromPassphrase(testPassphrase, salt, minIterations-1); ok {
+		t.Fatal("secretbox: derived key with too few iterations")
+	}
+}
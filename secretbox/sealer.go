@@ -0,0 +1,94 @@
+package secretbox
+
+import (
+	"io"
+
+	"github.com/kisom/aescrypt/guard"
+)
+
+// Options configures a Sealer.
+type Options struct {
+	// Rand is the source of random data for keys and IVs. If it is
+	// nil, the package-level PRNG is used.
+	Rand io.Reader
+}
+
+// A Sealer generates keys and seals boxes using its own source of
+// random data, rather than the package-level PRNG. A Sealer is safe
+// for concurrent use if its source of random data is. The
+// package-level functions that need random data use a default Sealer
+// that reads from PRNG.
+type Sealer struct {
+	rand io.Reader
+}
+
+var defaultSealer = NewSealer(nil)
+
+// NewSealer returns a Sealer configured by opts, which may be nil.
+func NewSealer(opts *Options) *Sealer {
+	s := &Sealer{}
+	if opts != nil {
+		s.rand = opts.Rand
+	}
+	return s
+}
+
+func (s *Sealer) reader() io.Reader {
+	if s.rand == nil {
+		return PRNG
+	}
+	return s.rand
+}
+
+// GenerateKey returns a key as the package-level GenerateKey does,
+// using the Sealer's source of random data.
+func (s *Sealer) GenerateKey() (Key, bool) {
+	var key Key = make([]byte, KeySize)
+
+	_, err := io.ReadFull(s.reader(), key)
+	return key, err == nil
+}
+
+// Seal seals a message as the package-level Seal does.
+func (s *Sealer) Seal(message []byte, key Key) (box []byte, ok bool) {
+	return s.SealWithAD(message, nil, key)
+}
+
+// SealE seals a message as the package-level SealE does.
+func (s *Sealer) SealE(message []byte, key Key) (box []byte, err error) {
+	return sealWithAD(s.reader(), message, nil, key)
+}
+
+// SealWithAD seals a message with associated data as the
+// package-level SealWithAD does.
+func (s *Sealer) SealWithAD(message, ad []byte, key Key) (box []byte, ok bool) {
+	box, err := sealWithAD(s.reader(), message, ad, key)
+	return box, err == nil
+}
+
+// NewCipher returns a Cipher for the key as the package-level
+// NewCipher does. The Cipher reads its IVs from the Sealer's source of
+// random data.
+func (s *Sealer) NewCipher(key Key) (*Cipher, bool) {
+	return newCipher(s, key)
+}
+
+// NewSealWriter returns a writer that seals a stream as the
+// package-level NewSealWriter does, using the Sealer's source of
+// random data.
+func (s *Sealer) NewSealWriter(w io.Writer, key Key) (io.WriteCloser, bool) {
+	return newSealWriter(s, w, key)
+}
+
+// SealWithPassphrase seals a message under a key derived from the
+// passphrase as the package-level SealWithPassphrase does.
+func (s *Sealer) SealWithPassphrase(message, passphrase []byte) (box []byte, ok bool) {
+	return sealWithPassphrase(s.reader(), message, passphrase)
+}
+
+// GenerateGuardedKey returns a key in locked memory as the
+// package-level GenerateGuardedKey does, using the Sealer's source of
+// random data.
+func (s *Sealer) GenerateGuardedKey() (*guard.Key, bool) {
+	return guard.Random(s.reader(), KeySize)
+}
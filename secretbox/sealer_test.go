@@ -0,0 +1,112 @@
+package secretbox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"sync"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestSealer(t *testing.T) {
+	defer func(r io.Reader) { PRNG = r }(PRNG)
+	PRNG = failingReader{}
+
+	iv := bytes.Repeat([]byte{0x42}, 16)
+	s := NewSealer(&Options{Rand: bytes.NewReader(iv)})
+	msg := []byte(testMessages[0])
+
+	box, ok := s.Seal(msg, testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: sealer failed to seal message")
+	} else if !bytes.Equal(box[:len(iv)], iv) {
+		t.Fatal("secretbox: sealer did not use its source of random data")
+	} else if out, ok := Open(box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: failed to open sealer box")
+	}
+
+	// The package-level functions use PRNG.
+	if _, ok = Seal(msg, testGoodKey); ok {
+		t.Fatal("secretbox: Seal should read from PRNG")
+	} else if _, ok = GenerateKey(); ok {
+		t.Fatal("secretbox: GenerateKey should read from PRNG")
+	}
+
+	// So do ciphers, streams, passphrase boxes and guarded keys,
+	// unless they come from a Sealer.
+	defer func(n int) { PassphraseIterations = n }(PassphraseIterations)
+	PassphraseIterations = minIterations
+
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create cipher")
+	} else if _, ok = c.SealTo(nil, msg); ok {
+		t.Fatal("secretbox: Cipher should read from PRNG")
+	} else if _, ok = NewSealWriter(new(bytes.Buffer), testGoodKey); ok {
+		t.Fatal("secretbox: NewSealWriter should read from PRNG")
+	} else if _, ok = SealWithPassphrase(msg, testPassphrase); ok {
+		t.Fatal("secretbox: SealWithPassphrase should read from PRNG")
+	} else if _, ok = GenerateGuardedKey(); ok {
+		t.Fatal("secretbox: GenerateGuardedKey should read from PRNG")
+	}
+
+	good := NewSealer(&Options{Rand: rand.Reader})
+	if c, ok = good.NewCipher(testGoodKey); !ok {
+		t.Fatal("secretbox: sealer failed to create cipher")
+	} else if box, ok = c.SealTo(nil, msg); !ok {
+		t.Fatal("secretbox: sealer cipher failed to seal message")
+	} else if out, ok := Open(box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: failed to open sealer cipher box")
+	}
+
+	var stream bytes.Buffer
+	w, ok := good.NewSealWriter(&stream, testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: sealer failed to create stream writer")
+	} else if _, err := w.Write(msg); err != nil {
+		t.Fatalf("secretbox: sealer failed to write stream: %v", err)
+	} else if err = w.Close(); err != nil {
+		t.Fatalf("secretbox: sealer failed to close stream: %v", err)
+	} else if out, err := openStream(stream.Bytes(), testGoodKey); err != nil || !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: failed to open sealer stream")
+	}
+
+	if box, ok = good.SealWithPassphrase(msg, testPassphrase); !ok {
+		t.Fatal("secretbox: sealer failed to seal with passphrase")
+	} else if out, ok := OpenWithPassphrase(box, testPassphrase); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("secretbox: failed to open sealer passphrase box")
+	}
+
+	gkey, ok := good.GenerateGuardedKey()
+	if !ok {
+		t.Fatal("secretbox: sealer failed to generate guarded key")
+	}
+	gkey.Destroy()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := NewSealer(&Options{Rand: rand.Reader})
+			key, ok := s.GenerateKey()
+			if !ok {
+				t.Error("secretbox: sealer failed to generate key")
+				return
+			}
+			for j := 0; j < 16; j++ {
+				if _, ok = s.SealWithAD(msg, msg, key); !ok {
+					t.Error("secretbox: sealer failed to seal message")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
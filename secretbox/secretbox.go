@@ -22,8 +22,12 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/binary"
 	"fmt"
 	"io"
+
+	"github.com/kisom/aescrypt/health"
+	"github.com/kisom/aescrypt/internal/selftest"
 )
 
 const cryptKeySize = 16
@@ -37,13 +41,10 @@ const KeySize = cryptKeySize + tagKeySize
 // Overhead is the number of bytes of overhead when boxing a message.
 const Overhead = aes.BlockSize + sha256.Size
 
-var (
-	errinvalidKeySize    = fmt.Errorf("invalid key size")
-	errinvalidCiphertext = fmt.Errorf("invalid ciphertext")
-)
-
-// The default source for random data is the crypto/rand package's Reader.
-var PRNG = rand.Reader
+// The default source for random data is the crypto/rand package's
+// Reader, wrapped in a health.Reader so that a failing source is
+// detected rather than causing keys and IVs to be reused.
+var PRNG io.Reader = health.NewReader(rand.Reader)
 
 type Key []byte
 type nonce []byte
@@ -52,22 +53,29 @@ type nonce []byte
 // a boolean indicating success. If the boolean returns false, the Key
 // value must be discarded.
 func GenerateKey() (Key, bool) {
-	var key Key = make([]byte, KeySize)
-
-	_, err := io.ReadFull(PRNG, key)
-	return key, err == nil
+	return defaultSealer.GenerateKey()
 }
 
-func generateNonce() (nonce, error) {
+func generateNonce(r io.Reader) (nonce, error) {
 	var n nonce = make([]byte, aes.BlockSize)
 
-	_, err := io.ReadFull(PRNG, n)
+	_, err := io.ReadFull(r, n)
 	return n, err
 }
 
-func encrypt(key []byte, in []byte) (out []byte, err error) {
+// newBlock returns the AES block cipher for a key. It refuses to
+// return one if a self-test has failed.
+func newBlock(key []byte) (cipher.Block, error) {
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	}
+	return aes.NewCipher(key)
+}
+
+func encrypt(r io.Reader, key []byte, in []byte) (out []byte, err error) {
 	var iv nonce
-	if iv, err = generateNonce(); err != nil {
+	if iv, err = generateNonce(r); err != nil {
+		err = fmt.Errorf("%w: %v", ErrRandomness, err)
 		return
 	}
 
@@ -77,7 +85,7 @@ func encrypt(key []byte, in []byte) (out []byte, err error) {
 		iv[i] = 0
 	}
 
-	c, err := aes.NewCipher(key)
+	c, err := newBlock(key)
 	if err != nil {
 		return
 	}
@@ -86,26 +94,58 @@ func encrypt(key []byte, in []byte) (out []byte, err error) {
 	return
 }
 
-func computeTag(key []byte, in []byte) (tag []byte) {
+// adLabel is used to derive the tag key for boxes with associated
+// data.
+var adLabel = []byte("secretbox associated data")
+
+// subkey derives a key for a separate use of a tag key, so that a tag
+// computed for one use is never valid for another.
+func subkey(key, label []byte) []byte {
 	h := hmac.New(sha256.New, key)
-	h.Write(in)
+	h.Write(label)
 	return h.Sum(nil)
 }
 
-func checkTag(key, in []byte) bool {
+// computeTag returns the HMAC of the concatenated inputs. If
+// associated data is present, it is authenticated ahead of the input,
+// prefixed with its length as a 64-bit big-endian integer, under a tag
+// key derived from key; otherwise, the box is tagged exactly as it was
+// before associated data was supported. Using a separate key means a
+// box with associated data can't be rewritten into one that opens
+// without it.
+func computeTag(key []byte, ad []byte, in ...[]byte) (tag []byte) {
+	if len(ad) > 0 {
+		key = subkey(key, adLabel)
+		defer zero(key)
+	}
+
+	h := hmac.New(sha256.New, key)
+	if len(ad) > 0 {
+		var adLen [8]byte
+		binary.BigEndian.PutUint64(adLen[:], uint64(len(ad)))
+		h.Write(adLen[:])
+		h.Write(ad)
+	}
+	for _, p := range in {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+func checkTag(key, ad, in []byte) bool {
 	ctlen := len(in) - sha256.Size
 	tag := in[ctlen:]
 	ct := in[:ctlen]
-	actualTag := computeTag(key, ct)
+	actualTag := computeTag(key, ad, ct)
 	return subtle.ConstantTimeCompare(tag, actualTag) == 1
 }
 
 func decrypt(key []byte, in []byte) (out []byte, err error) {
 	if len(in) < aes.BlockSize {
-		return nil, errinvalidCiphertext
+		return nil, ErrMalformed
 	}
 
-	c, err := aes.NewCipher(key)
+	c, err := newBlock(key)
 	if err != nil {
 		return
 	}
@@ -123,18 +163,35 @@ func decrypt(key []byte, in []byte) (out []byte, err error) {
 // true, the message was successfully sealed. The box will be Overhead
 // bytes longer than the message.
 func Seal(message []byte, key Key) (box []byte, ok bool) {
+	return defaultSealer.Seal(message, key)
+}
+
+// SealE seals a message as Seal does, returning an error describing
+// why sealing failed rather than a boolean.
+func SealE(message []byte, key Key) (box []byte, err error) {
+	return defaultSealer.SealE(message, key)
+}
+
+// SealWithAD seals a message as Seal does, additionally
+// authenticating the associated data ad. The associated data is not
+// stored in the box; the same data must be passed to OpenWithAD to
+// open it. A box sealed with empty associated data is identical to
+// one produced by Seal.
+func SealWithAD(message, ad []byte, key Key) (box []byte, ok bool) {
+	return defaultSealer.SealWithAD(message, ad, key)
+}
+
+func sealWithAD(r io.Reader, message, ad []byte, key Key) (box []byte, err error) {
 	if !KeyIsSuitable(key) {
-		return
+		return nil, ErrInvalidKey
 	}
 
-	ct, err := encrypt(key[:cryptKeySize], message)
+	ct, err := encrypt(r, key[:cryptKeySize], message)
 	if err != nil {
-		return
+		return nil, err
 	}
-	tag := computeTag(key[cryptKeySize:], ct)
-	box = append(ct, tag...)
-	ok = true
-	return
+	tag := computeTag(key[cryptKeySize:], ad, ct)
+	return append(ct, tag...), nil
 }
 
 // Open authenticates and decrypts a sealed message, also returning
@@ -142,21 +199,37 @@ func Seal(message []byte, key Key) (box []byte, ok bool) {
 // message must be discarded. The returned message will be Overhead
 // bytes shorter than the box.
 func Open(box []byte, key Key) (message []byte, ok bool) {
+	return OpenWithAD(box, nil, key)
+}
+
+// OpenE opens a box as Open does, returning an error describing why
+// opening failed rather than a boolean. A box that was sealed under a
+// different key and a box that has been modified both return
+// ErrAuthFailed.
+func OpenE(box []byte, key Key) (message []byte, err error) {
+	return openWithAD(box, nil, key)
+}
+
+// OpenWithAD authenticates and decrypts a box sealed with
+// SealWithAD. It will fail if ad is not the associated data the box
+// was sealed with.
+func OpenWithAD(box, ad []byte, key Key) (message []byte, ok bool) {
+	message, err := openWithAD(box, ad, key)
+	return message, err == nil
+}
+
+func openWithAD(box, ad []byte, key Key) (message []byte, err error) {
 	if !KeyIsSuitable(key) {
-		return
-	} else if box == nil {
-		return
+		return nil, ErrInvalidKey
 	} else if len(box) < Overhead {
-		return
+		return nil, ErrMalformed
 	}
 
 	msgLen := len(box) - sha256.Size
-	if !checkTag(key[cryptKeySize:], box) {
-		return nil, ok
+	if !checkTag(key[cryptKeySize:], ad, box) {
+		return nil, ErrAuthFailed
 	}
-	message, err := decrypt(key[:cryptKeySize], box[:msgLen])
-	ok = err == nil
-	return
+	return decrypt(key[:cryptKeySize], box[:msgLen])
 }
 
 // IsKeySuitable returns true if the byte slice represents a valid
@@ -164,3 +237,10 @@ func Open(box []byte, key Key) (message []byte, ok bool) {
 func KeyIsSuitable(key []byte) bool {
 	return subtle.ConstantTimeEq(int32(len(key)), int32(KeySize)) == 1
 }
+
+// Zero out a byte slice.
+func zero(in []byte) {
+	for i := range in {
+		in[i] = 0
+	}
+}
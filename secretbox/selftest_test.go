@@ -0,0 +1,46 @@
+package secretbox
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("secretbox: self-test failed: %v", err)
+	}
+
+	a, ok := NewAEAD(testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create AEAD")
+	}
+
+	defer func(v string) { selfTestBox = v }(selfTestBox)
+	defer selftest.Reset()
+
+	// Corrupt the last byte of the expected tag.
+	b := []byte(selfTestBox)
+	b[len(b)-1] ^= 1
+	selfTestBox = string(b)
+	if err := SelfTest(); !errors.Is(err, ErrSelfTest) {
+		t.Fatalf("secretbox: expected ErrSelfTest, got %v", err)
+	}
+
+	if _, ok := Seal([]byte(testMessages[0]), testGoodKey); ok {
+		t.Fatal("secretbox: sealed after a self-test failure")
+	} else if _, ok = NewCipher(testGoodKey); ok {
+		t.Fatal("secretbox: created a cipher after a self-test failure")
+	} else if _, ok = NewAEAD(testGoodKey); ok {
+		t.Fatal("secretbox: created an AEAD after a self-test failure")
+	}
+
+	// An AEAD created before the failure must not seal either.
+	defer func() {
+		if r := recover(); r != ErrSelfTest {
+			t.Fatalf("secretbox: expected AEAD to panic with ErrSelfTest, got %v", r)
+		}
+	}()
+	a.Seal(nil, make([]byte, a.NonceSize()), []byte(testMessages[0]), nil)
+}
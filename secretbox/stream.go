@@ -0,0 +1,261 @@
+package secretbox
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// A stream is a random stream identifier followed by a sequence of
+// chunks. Each chunk is laid out as
+//
+//	flag (1 byte) || length (4 bytes) || IV || ciphertext || tag
+//
+// where the tag is computed over the IV and ciphertext with the stream
+// identifier, the chunk counter, and the flag and length as associated
+// data. The flag
+// marks the final chunk of the stream; the counter prevents chunks
+// from being reordered or dropped, and the final flag prevents the
+// stream from being truncated or extended.
+const (
+	streamIDSize      = 16
+	chunkHeaderSize   = 5
+	streamChunkSize   = 64 * 1024
+	chunkFinal        = 1
+	chunkIntermediate = 0
+)
+
+var (
+	errStreamClosed    = fmt.Errorf("secretbox: write to closed stream")
+	errStreamTruncated = fmt.Errorf("%w: truncated stream", ErrMalformed)
+	errStreamExtended  = fmt.Errorf("%w: data after final chunk", ErrMalformed)
+	errStreamChunk     = fmt.Errorf("%w: invalid stream chunk", ErrAuthFailed)
+)
+
+// chunkAD builds the associated data authenticated with each chunk.
+func chunkAD(id []byte, counter uint64, hdr []byte) []byte {
+	ad := make([]byte, 0, streamIDSize+8+chunkHeaderSize)
+	ad = append(ad, id...)
+	ad = binary.BigEndian.AppendUint64(ad, counter)
+	return append(ad, hdr...)
+}
+
+type sealWriter struct {
+	sealer  *Sealer
+	w       io.Writer
+	key     Key
+	id      []byte
+	buf     []byte
+	counter uint64
+	started bool
+	err     error
+}
+
+// NewSealWriter returns a writer that seals everything written to it
+// into a chunked stream written to w, and a boolean indicating
+// success. The stream is not complete until Close is called; Close
+// does not close w.
+func NewSealWriter(w io.Writer, key Key) (io.WriteCloser, bool) {
+	return defaultSealer.NewSealWriter(w, key)
+}
+
+func newSealWriter(s *Sealer, w io.Writer, key Key) (io.WriteCloser, bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	}
+
+	id := make([]byte, streamIDSize)
+	if _, err := io.ReadFull(s.reader(), id); err != nil {
+		return nil, false
+	}
+
+	return &sealWriter{
+		sealer: s,
+		w:      w,
+		key:    key,
+		id:     id,
+		buf:    make([]byte, 0, streamChunkSize),
+	}, true
+}
+
+func (sw *sealWriter) writeChunk(chunk []byte, flag byte) {
+	if !sw.started {
+		if _, sw.err = sw.w.Write(sw.id); sw.err != nil {
+			return
+		}
+		sw.started = true
+	}
+
+	hdr := make([]byte, chunkHeaderSize)
+	hdr[0] = flag
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(chunk)))
+
+	var ct []byte
+	ct, sw.err = encrypt(sw.sealer.reader(), sw.key[:cryptKeySize], chunk)
+	if sw.err != nil {
+		return
+	}
+	tag := computeTag(sw.key[cryptKeySize:], chunkAD(sw.id, sw.counter, hdr), ct)
+	sw.counter++
+
+	out := make([]byte, 0, len(hdr)+len(ct)+len(tag))
+	out = append(out, hdr...)
+	out = append(out, ct...)
+	out = append(out, tag...)
+	_, sw.err = sw.w.Write(out)
+}
+
+// Write seals p into the stream. Data is buffered until a full chunk
+// is available.
+func (sw *sealWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if sw.err != nil {
+			return n, sw.err
+		}
+
+		// A full chunk is only flushed once more data arrives, so
+		// that the final chunk is never empty unless the stream is.
+		if len(sw.buf) == streamChunkSize {
+			sw.writeChunk(sw.buf, chunkIntermediate)
+			sw.buf = sw.buf[:0]
+			continue
+		}
+
+		m := streamChunkSize - len(sw.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		sw.buf = append(sw.buf, p[:m]...)
+		p = p[m:]
+		n += m
+	}
+	return n, sw.err
+}
+
+// Close writes the final chunk of the stream.
+func (sw *sealWriter) Close() error {
+	if sw.err != nil {
+		return sw.err
+	}
+
+	sw.writeChunk(sw.buf, chunkFinal)
+	zero(sw.buf)
+	if sw.err == nil {
+		sw.err = errStreamClosed
+		return nil
+	}
+	return sw.err
+}
+
+type openReader struct {
+	r       io.Reader
+	key     Key
+	id      []byte
+	buf     []byte
+	plain   []byte
+	counter uint64
+	final   bool
+	err     error
+}
+
+// NewOpenReader returns a reader that authenticates and decrypts a
+// stream produced by a writer returned from NewSealWriter, and a
+// boolean indicating success. Each chunk is authenticated before any
+// of its contents are returned; a stream that has been modified,
+// reordered, truncated or extended returns an error from Read.
+func NewOpenReader(r io.Reader, key Key) (io.Reader, bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	}
+
+	return &openReader{
+		r:   r,
+		key: key,
+	}, true
+}
+
+func (or *openReader) readChunk() error {
+	if or.id == nil {
+		id := make([]byte, streamIDSize)
+		if _, err := io.ReadFull(or.r, id); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return errStreamTruncated
+			}
+			return err
+		}
+		or.id = id
+	}
+
+	hdr := make([]byte, chunkHeaderSize)
+	if _, err := io.ReadFull(or.r, hdr); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errStreamTruncated
+		}
+		return err
+	}
+
+	flag := hdr[0]
+	ptLen := int(binary.BigEndian.Uint32(hdr[1:]))
+	if flag != chunkFinal && flag != chunkIntermediate {
+		return ErrMalformed
+	} else if ptLen > streamChunkSize {
+		return ErrMalformed
+	}
+
+	chunkLen := ptLen + Overhead
+	if cap(or.buf) < chunkLen {
+		or.buf = make([]byte, chunkLen)
+	}
+	chunk := or.buf[:chunkLen]
+	if _, err := io.ReadFull(or.r, chunk); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errStreamTruncated
+		}
+		return err
+	}
+
+	if !checkTag(or.key[cryptKeySize:], chunkAD(or.id, or.counter, hdr), chunk) {
+		return errStreamChunk
+	}
+
+	plain, err := decrypt(or.key[:cryptKeySize], chunk[:chunkLen-sha256.Size])
+	if err != nil {
+		return err
+	}
+
+	or.counter++
+	or.plain = plain
+	or.final = flag == chunkFinal
+	return nil
+}
+
+// Read returns decrypted data from the stream. It returns io.EOF only
+// after the final chunk has been authenticated and the underlying
+// reader is exhausted.
+func (or *openReader) Read(p []byte) (n int, err error) {
+	for len(or.plain) == 0 {
+		if or.err != nil {
+			return 0, or.err
+		}
+
+		if or.final {
+			var extra [1]byte
+			m, err := io.ReadFull(or.r, extra[:])
+			if m != 0 {
+				or.err = errStreamExtended
+			} else if err == io.EOF {
+				or.err = io.EOF
+			} else {
+				or.err = err
+			}
+			continue
+		}
+
+		or.err = or.readChunk()
+	}
+
+	n = copy(p, or.plain)
+	or.plain = or.plain[n:]
+	return n, nil
+}
@@ -0,0 +1,159 @@
+package secretbox
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func sealStream(t *testing.T, message []byte, key Key) []byte {
+	var out bytes.Buffer
+	w, ok := NewSealWriter(&out, key)
+	if !ok {
+		t.Fatal("secretbox: failed to create seal writer")
+	}
+
+	// Write in odd-sized pieces to exercise chunk boundaries.
+	for len(message) > 0 {
+		n := 1000
+		if n > len(message) {
+			n = len(message)
+		}
+		if _, err := w.Write(message[:n]); err != nil {
+			t.Fatalf("secretbox: stream write failed: %v", err)
+		}
+		message = message[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("secretbox: stream close failed: %v", err)
+	}
+	return out.Bytes()
+}
+
+func openStream(stream []byte, key Key) ([]byte, error) {
+	r, ok := NewOpenReader(bytes.NewReader(stream), key)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return ioutil.ReadAll(r)
+}
+
+func testStreamMessage() []byte {
+	message := make([]byte, 3*streamChunkSize+1234)
+	for i := range message {
+		message[i] = byte(i * 7)
+	}
+	return message
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	sizes := []int{0, 1, streamChunkSize - 1, streamChunkSize, streamChunkSize + 1, 3*streamChunkSize + 1234}
+	full := testStreamMessage()
+	for _, size := range sizes {
+		message := full[:size]
+		stream := sealStream(t, message, testGoodKey)
+
+		out, err := openStream(stream, testGoodKey)
+		if err != nil {
+			t.Fatalf("secretbox: failed to open %d byte stream: %v", size, err)
+		} else if !bytes.Equal(out, message) {
+			t.Fatalf("secretbox: %d byte stream did not round trip", size)
+		}
+
+		if _, err = openStream(stream, testBadKey); err == nil {
+			t.Fatalf("secretbox: %d byte stream opened with the wrong key", size)
+		}
+	}
+}
+
+// chunkOffsets returns the offset of each chunk in a sealed stream.
+func chunkOffsets(stream []byte) []int {
+	var offsets []int
+	off := streamIDSize
+	for off < len(stream) {
+		offsets = append(offsets, off)
+		ptLen := int(stream[off+1])<<24 | int(stream[off+2])<<16 |
+			int(stream[off+3])<<8 | int(stream[off+4])
+		off += chunkHeaderSize + ptLen + Overhead
+	}
+	return offsets
+}
+
+func TestStreamTampering(t *testing.T) {
+	message := testStreamMessage()
+	stream := sealStream(t, message, testGoodKey)
+	offsets := chunkOffsets(stream)
+	if len(offsets) != 4 {
+		t.Fatalf("secretbox: expected 4 chunks, have %d", len(offsets))
+	}
+
+	if _, err := openStream(mutate(stream), testGoodKey); err == nil {
+		t.Fatal("secretbox: modified stream should not open")
+	}
+
+	// Swapping two full chunks must be detected.
+	chunkLen := offsets[1] - offsets[0]
+	swapped := make([]byte, 0, len(stream))
+	swapped = append(swapped, stream[:offsets[0]]...)
+	swapped = append(swapped, stream[offsets[1]:offsets[2]]...)
+	swapped = append(swapped, stream[offsets[0]:offsets[0]+chunkLen]...)
+	swapped = append(swapped, stream[offsets[2]:]...)
+	if _, err := openStream(swapped, testGoodKey); err == nil {
+		t.Fatal("secretbox: reordered stream should not open")
+	}
+
+	// Dropping the final chunk must be detected.
+	if _, err := openStream(stream[:offsets[3]], testGoodKey); err != errStreamTruncated {
+		t.Fatalf("secretbox: truncated stream should fail with %v, got %v", errStreamTruncated, err)
+	}
+
+	// Cutting the stream off inside its identifier must be reported
+	// as truncation too.
+	if _, err := openStream(stream[:streamIDSize-1], testGoodKey); err != errStreamTruncated {
+		t.Fatalf("secretbox: stream truncated in its ID should fail with %v, got %v", errStreamTruncated, err)
+	}
+
+	// Dropping a middle chunk must be detected.
+	dropped := append(append([]byte{}, stream[:offsets[1]]...), stream[offsets[2]:]...)
+	if _, err := openStream(dropped, testGoodKey); err == nil {
+		t.Fatal("secretbox: stream with a missing chunk should not open")
+	}
+
+	// Appending data after the final chunk must be detected.
+	extended := append(append([]byte{}, stream...), stream[offsets[3]:]...)
+	if _, err := openStream(extended, testGoodKey); err != errStreamExtended {
+		t.Fatalf("secretbox: extended stream should fail with %v, got %v", errStreamExtended, err)
+	}
+
+	// Chunks from another stream under the same key must not be accepted.
+	other := sealStream(t, message, testGoodKey)
+	spliced := append(append([]byte{}, stream[:offsets[3]]...), other[offsets[3]:]...)
+	if _, err := openStream(spliced, testGoodKey); err == nil {
+		t.Fatal("secretbox: spliced stream should not open")
+	}
+}
+
+func TestStreamWriteAfterClose(t *testing.T) {
+	w, ok := NewSealWriter(ioutil.Discard, testGoodKey)
+	if !ok {
+		t.Fatal("secretbox: failed to create seal writer")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("secretbox: stream close failed: %v", err)
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Fatal("secretbox: write after close should fail")
+	}
+}
+
+func TestStreamBadKey(t *testing.T) {
+	if _, ok := NewSealWriter(ioutil.Discard, testGoodKey[1:]); ok {
+		t.Fatal("secretbox: seal writer accepted a short key")
+	}
+	if _, ok := NewOpenReader(bytes.NewReader(nil), testGoodKey[1:]); ok {
+		t.Fatal("secretbox: open reader accepted a short key")
+	}
+	if _, err := openStream(nil, testGoodKey); err != errStreamTruncated {
+		t.Fatalf("secretbox: empty stream should fail with %v, got %v", errStreamTruncated, err)
+	}
+}
@@ -0,0 +1,183 @@
+package secretbox
+
+import (
+	"errors"
+	"sync"
+)
+
+// Each box uses a random 128-bit IV, and encrypts its message with
+// AES in CTR mode. Two boxes under the same key whose counter ranges
+// overlap reuse keystream. Sealing 2^48 boxes keeps the chance of any
+// two IVs colliding below 2^-32, and sealing at most 2^52 bytes (2^48
+// blocks) under a key keeps the chance of any overlap in the counter
+// ranges at a similar level. These are the default usage limits.
+const (
+	DefaultMaxMessages = 1 << 48
+	DefaultMaxBytes    = 1 << 52
+)
+
+// ErrKeyWornOut is returned by a UsageSealer once sealing another box
+// would exceed its key's usage limits. The key must be replaced.
+var ErrKeyWornOut = errors.New("secretbox: key usage limit reached")
+
+// Usage records the number of boxes and message bytes sealed under a
+// key.
+type Usage struct {
+	Messages uint64
+	Bytes    uint64
+}
+
+// UsageOptions configures a UsageSealer.
+type UsageOptions struct {
+	// Options configures the source of random data.
+	Options
+
+	// MaxMessages and MaxBytes are the usage limits for the key.
+	// Zero values are replaced by DefaultMaxMessages and
+	// DefaultMaxBytes; larger values are reduced to them.
+	MaxMessages uint64
+	MaxBytes    uint64
+
+	// WarnMessages and WarnBytes are optional lower thresholds. The
+	// first time either is reached, OnWarn is called with the
+	// current usage, so that the key may be rotated before it wears
+	// out. Zero values disable the threshold.
+	WarnMessages uint64
+	WarnBytes    uint64
+	OnWarn       func(Usage)
+
+	// Usage is the usage already recorded for the key, such as
+	// usage persisted before a restart.
+	Usage Usage
+}
+
+// A UsageSealer seals and opens boxes under a single key, counting
+// the boxes and bytes it seals. It refuses to seal a box that would
+// exceed the key's usage limits. A UsageSealer is safe for concurrent
+// use if its source of random data is.
+type UsageSealer struct {
+	sealer *Sealer
+	key    Key
+
+	mu        sync.Mutex
+	usage     Usage
+	maxMsgs   uint64
+	maxBytes  uint64
+	warnMsgs  uint64
+	warnBytes uint64
+	onWarn    func(Usage)
+	warned    bool
+}
+
+func clampLimit(limit, max uint64) uint64 {
+	if limit == 0 || limit > max {
+		return max
+	}
+	return limit
+}
+
+// NewUsageSealer returns a UsageSealer for a copy of the key,
+// configured by opts, which may be nil, and a boolean indicating
+// success.
+func NewUsageSealer(key Key, opts *UsageOptions) (*UsageSealer, bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	}
+	if opts == nil {
+		opts = &UsageOptions{}
+	}
+
+	u := &UsageSealer{
+		sealer:    NewSealer(&opts.Options),
+		key:       copyKey(key),
+		usage:     opts.Usage,
+		maxMsgs:   clampLimit(opts.MaxMessages, DefaultMaxMessages),
+		maxBytes:  clampLimit(opts.MaxBytes, DefaultMaxBytes),
+		warnMsgs:  opts.WarnMessages,
+		warnBytes: opts.WarnBytes,
+		onWarn:    opts.OnWarn,
+	}
+	return u, true
+}
+
+// reserve records a box of n bytes, returning ErrKeyWornOut if it
+// would exceed the usage limits. If a warning threshold has just been
+// crossed, it also returns the warning callback to call.
+func (u *UsageSealer) reserve(n uint64) (func(Usage), Usage, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.usage.Messages >= u.maxMsgs {
+		return nil, u.usage, ErrKeyWornOut
+	} else if u.usage.Bytes > u.maxBytes || n > u.maxBytes-u.usage.Bytes {
+		return nil, u.usage, ErrKeyWornOut
+	}
+
+	u.usage.Messages++
+	u.usage.Bytes += n
+
+	if u.warned || u.onWarn == nil {
+		return nil, u.usage, nil
+	}
+	if (u.warnMsgs != 0 && u.usage.Messages >= u.warnMsgs) ||
+		(u.warnBytes != 0 && u.usage.Bytes >= u.warnBytes) {
+		u.warned = true
+		return u.onWarn, u.usage, nil
+	}
+	return nil, u.usage, nil
+}
+
+func (u *UsageSealer) sealWithAD(message, ad []byte) (box []byte, err error) {
+	warn, usage, err := u.reserve(uint64(len(message)))
+	if err != nil {
+		return nil, err
+	}
+	if warn != nil {
+		warn(usage)
+	}
+	return sealWithAD(u.sealer.reader(), message, ad, u.key)
+}
+
+// Seal seals a message as Seal does. It returns false once the key's
+// usage limits would be exceeded.
+func (u *UsageSealer) Seal(message []byte) (box []byte, ok bool) {
+	box, err := u.sealWithAD(message, nil)
+	return box, err == nil
+}
+
+// SealE seals a message as SealE does, returning ErrKeyWornOut if
+// the key's usage limits would be exceeded. A box that fails to seal
+// for another reason still counts towards the limits.
+func (u *UsageSealer) SealE(message []byte) (box []byte, err error) {
+	return u.sealWithAD(message, nil)
+}
+
+// SealWithAD seals a message with associated data as SealWithAD
+// does. It returns false once the key's usage limits would be
+// exceeded.
+func (u *UsageSealer) SealWithAD(message, ad []byte) (box []byte, ok bool) {
+	box, err := u.sealWithAD(message, ad)
+	return box, err == nil
+}
+
+// Open opens a box sealed under the UsageSealer's key. Opening boxes
+// does not count towards the usage limits.
+func (u *UsageSealer) Open(box []byte) (message []byte, ok bool) {
+	return Open(box, u.key)
+}
+
+// Usage returns the usage recorded for the key, which may be
+// persisted and passed back in UsageOptions.
+func (u *UsageSealer) Usage() Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.usage
+}
+
+// WornOut returns true once either of the key's usage limits has
+// been reached.
+func (u *UsageSealer) WornOut() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.usage.Messages >= u.maxMsgs || u.usage.Bytes >= u.maxBytes
+}
@@ -0,0 +1,25 @@
+package cryptobox
+
+import (
+	"errors"
+
+	"github.com/kisom/aescrypt/box"
+	"github.com/kisom/aescrypt/secretbox"
+	"github.com/kisom/aescrypt/stoutbox"
+	"github.com/kisom/aescrypt/strongbox"
+)
+
+// SelfTest runs the known-answer self-tests of the secretbox,
+// strongbox, box and stoutbox packages, and returns an error
+// describing every test that failed. If any test fails, every package
+// refuses to seal or open boxes. Building with the cryptobox_selftest
+// tag runs each package's self-test when it is initialised, before
+// any box can be sealed or opened.
+func SelfTest() error {
+	return errors.Join(
+		secretbox.SelfTest(),
+		strongbox.SelfTest(),
+		box.SelfTest(),
+		stoutbox.SelfTest(),
+	)
+}
@@ -0,0 +1,53 @@
+package cryptobox
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kisom/aescrypt/box"
+	"github.com/kisom/aescrypt/gcmbox"
+	"github.com/kisom/aescrypt/internal/selftest"
+	"github.com/kisom/aescrypt/secretbox"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("cryptobox: self-test failed: %v", err)
+	}
+
+	keys := testKeys(t)
+	sbox, ok := Seal(testMessage, keys[SuiteSecretbox], SuiteSecretbox)
+	if !ok {
+		t.Fatal("cryptobox: failed to seal message")
+	}
+	priv, pub, ok := box.GenerateKey()
+	if !ok {
+		t.Fatal("cryptobox: failed to generate key")
+	}
+	pbox, ok := box.Seal(testMessage, pub)
+	if !ok {
+		t.Fatal("cryptobox: failed to seal message")
+	}
+
+	selftest.Fail()
+	defer selftest.Reset()
+
+	for suite, key := range keys {
+		if _, ok = Seal(testMessage, key, suite); ok {
+			t.Fatalf("cryptobox: suite %d sealed after a self-test failure", suite)
+		}
+	}
+	if _, ok = Open(sbox, keys[SuiteSecretbox]); ok {
+		t.Fatal("cryptobox: opened a box after a self-test failure")
+	} else if _, ok = gcmbox.Open(make([]byte, gcmbox.Overhead), keys[SuiteGCMBox]); ok {
+		t.Fatal("cryptobox: gcmbox opened a box after a self-test failure")
+	} else if _, err := box.OpenE(pbox, priv); !errors.Is(err, box.ErrSelfTest) {
+		t.Fatalf("cryptobox: expected box.ErrSelfTest, got %v", err)
+	} else if _, err = secretbox.SealE(testMessage, keys[SuiteSecretbox]); !errors.Is(err, secretbox.ErrSelfTest) {
+		t.Fatalf("cryptobox: expected secretbox.ErrSelfTest, got %v", err)
+	}
+
+	if err := SelfTest(); !errors.Is(err, secretbox.ErrSelfTest) || !errors.Is(err, box.ErrSelfTest) {
+		t.Fatalf("cryptobox: self-test should fail once a failure is recorded: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Package shamir implements Shamir's secret sharing over GF(256).
+
+A secret is split into n shares, any k of which recover it; fewer
+than k shares reveal nothing about it. Each byte of the secret is
+shared independently, as the constant term of a random polynomial of
+degree k-1 whose value at x is stored in the share with that X
+coordinate. A share is the X coordinate, between 1 and 255, followed
+by one byte per byte of the secret.
+
+The field is GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1.
+Field arithmetic does not use lookup tables or branch on its inputs.
+
+Shares are not authenticated: combining a modified share, or fewer
+than k shares, returns the wrong secret rather than failing. Callers
+should authenticate the secret, such as by using it as a key for a
+box.
+*/
+package shamir
+
+import (
+	"crypto/rand"
+	"io"
+
+	"github.com/kisom/aescrypt/health"
+)
+
+// MaxShares is the largest number of shares a secret may be split
+// into.
+const MaxShares = 255
+
+// The default source for random data is the crypto/rand package's
+// Reader, wrapped in a health.Reader.
+var PRNG io.Reader = health.NewReader(rand.Reader)
+
+// gfMul multiplies two elements of GF(256).
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		// mask is 0xff if the low bit of b is set, and 0 otherwise.
+		mask := -(b & 1)
+		p ^= a & mask
+		carry := -(a >> 7)
+		a = (a << 1) ^ (0x1b & carry)
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns the multiplicative inverse of a, which is a^254. The
+// inverse of zero is zero.
+func gfInv(a byte) byte {
+	b := gfMul(a, a)   // a^2
+	c := gfMul(a, b)   // a^3
+	b = gfMul(c, c)    // a^6
+	b = gfMul(b, b)    // a^12
+	c = gfMul(b, c)    // a^15
+	b = gfMul(b, b)    // a^24
+	b = gfMul(b, b)    // a^48
+	b = gfMul(b, c)    // a^63
+	b = gfMul(b, b)    // a^126
+	b = gfMul(a, b)    // a^127
+	return gfMul(b, b) // a^254
+}
+
+// Split splits secret into n shares, any k of which recover it, and
+// returns the shares and a boolean indicating success. k must be at
+// least 1 and no greater than n, and n no greater than MaxShares.
+func Split(secret []byte, n, k int) ([][]byte, bool) {
+	return SplitFrom(PRNG, secret, n, k)
+}
+
+// SplitFrom splits a secret as Split does, reading the polynomials'
+// coefficients from r.
+func SplitFrom(r io.Reader, secret []byte, n, k int) ([][]byte, bool) {
+	if len(secret) == 0 || k < 1 || n < k || n > MaxShares {
+		return nil, false
+	}
+
+	// coeffs holds the k-1 random coefficients for each byte of the
+	// secret.
+	coeffs := make([]byte, len(secret)*(k-1))
+	defer zero(coeffs)
+	if _, err := io.ReadFull(r, coeffs); err != nil {
+		return nil, false
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		x := byte(i + 1)
+		share := make([]byte, len(secret)+1)
+		share[0] = x
+		for j, s := range secret {
+			// Evaluate the polynomial by Horner's method.
+			poly := coeffs[j*(k-1) : (j+1)*(k-1)]
+			var y byte
+			for c := len(poly) - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ poly[c]
+			}
+			share[j+1] = gfMul(y, x) ^ s
+		}
+		shares[i] = share
+	}
+	return shares, true
+}
+
+// Combine recovers a secret from shares, and returns false if the
+// shares are malformed. If fewer shares are given than were needed,
+// the secret returned is wrong.
+func Combine(shares [][]byte) ([]byte, bool) {
+	if len(shares) == 0 || len(shares[0]) < 2 {
+		return nil, false
+	}
+
+	size := len(shares[0]) - 1
+	for i, share := range shares {
+		if len(share) != size+1 || share[0] == 0 {
+			return nil, false
+		}
+		for _, other := range shares[:i] {
+			if other[0] == share[0] {
+				return nil, false
+			}
+		}
+	}
+
+	// Interpolate the polynomials at zero. The Lagrange basis
+	// polynomial for share i at zero is the product of x_j / (x_j -
+	// x_i) over the other shares; subtraction in GF(256) is XOR.
+	secret := make([]byte, size)
+	for i, share := range shares {
+		basis := byte(1)
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			basis = gfMul(basis, gfMul(other[0], gfInv(other[0]^share[0])))
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(basis, share[b+1])
+		}
+	}
+	return secret, true
+}
+
+func zero(in []byte) {
+	for i := range in {
+		in[i] = 0
+	}
+}
@@ -0,0 +1,111 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+var testSecret = []byte("Ah! Curse your sudden but inevitable betrayal!")
+
+func TestField(t *testing.T) {
+	// The example from FIPS 197, section 4.2.
+	if p := gfMul(0x57, 0x83); p != 0xc1 {
+		t.Fatalf("shamir: 0x57 * 0x83 should be 0xc1, not %#x", p)
+	}
+
+	for a := 1; a < 256; a++ {
+		if p := gfMul(byte(a), gfInv(byte(a))); p != 1 {
+			t.Fatalf("shamir: %#x times its inverse is %#x", a, p)
+		}
+	}
+	if gfInv(0) != 0 {
+		t.Fatal("shamir: the inverse of zero should be zero")
+	}
+}
+
+// subsets calls f with every subset of k of the shares.
+func subsets(shares [][]byte, k int, f func([][]byte)) {
+	var walk func(start int, chosen [][]byte)
+	walk = func(start int, chosen [][]byte) {
+		if len(chosen) == k {
+			f(chosen)
+			return
+		}
+		for i := start; i < len(shares); i++ {
+			walk(i+1, append(chosen, shares[i]))
+		}
+	}
+	walk(0, nil)
+}
+
+func TestSplitCombine(t *testing.T) {
+	for _, p := range []struct{ n, k int }{{1, 1}, {3, 1}, {3, 2}, {5, 3}, {5, 5}} {
+		shares, ok := Split(testSecret, p.n, p.k)
+		if !ok {
+			t.Fatalf("shamir: failed to split secret %d of %d", p.k, p.n)
+		} else if len(shares) != p.n {
+			t.Fatalf("shamir: expected %d shares, got %d", p.n, len(shares))
+		}
+
+		for k := p.k; k <= p.n; k++ {
+			subsets(shares, k, func(chosen [][]byte) {
+				secret, ok := Combine(chosen)
+				if !ok {
+					t.Fatal("shamir: failed to combine shares")
+				} else if !bytes.Equal(secret, testSecret) {
+					t.Fatalf("shamir: %d of %d shares did not recover the secret", k, p.n)
+				}
+			})
+		}
+
+		if p.k == 1 {
+			continue
+		}
+		subsets(shares, p.k-1, func(chosen [][]byte) {
+			secret, ok := Combine(chosen)
+			if !ok {
+				t.Fatal("shamir: failed to combine shares")
+			} else if bytes.Equal(secret, testSecret) {
+				t.Fatalf("shamir: %d of %d shares recovered the secret", p.k-1, p.n)
+			}
+		})
+	}
+
+	shares, ok := Split(testSecret, MaxShares, 2)
+	if !ok {
+		t.Fatal("shamir: failed to split secret into the maximum number of shares")
+	} else if secret, ok := Combine(shares[MaxShares-2:]); !ok || !bytes.Equal(secret, testSecret) {
+		t.Fatal("shamir: failed to recover the secret")
+	}
+}
+
+func TestBadParameters(t *testing.T) {
+	for _, p := range []struct{ n, k int }{{3, 0}, {2, 3}, {MaxShares + 1, 2}} {
+		if _, ok := Split(testSecret, p.n, p.k); ok {
+			t.Fatalf("shamir: split secret %d of %d", p.k, p.n)
+		}
+	}
+	if _, ok := Split(nil, 3, 2); ok {
+		t.Fatal("shamir: split an empty secret")
+	}
+	if _, ok := SplitFrom(bytes.NewReader(nil), testSecret, 3, 2); ok {
+		t.Fatal("shamir: split a secret without random data")
+	}
+
+	shares, ok := Split(testSecret, 3, 2)
+	if !ok {
+		t.Fatal("shamir: failed to split secret")
+	}
+	if _, ok = Combine([][]byte{shares[0], shares[0]}); ok {
+		t.Fatal("shamir: combined duplicate shares")
+	} else if _, ok = Combine([][]byte{shares[0], shares[1][:5]}); ok {
+		t.Fatal("shamir: combined shares of different lengths")
+	} else if _, ok = Combine(nil); ok {
+		t.Fatal("shamir: combined no shares")
+	}
+
+	shares[1][0] = 0
+	if _, ok = Combine(shares[:2]); ok {
+		t.Fatal("shamir: combined a share with X coordinate zero")
+	}
+}
@@ -0,0 +1,95 @@
+package stoutbox
+
+import "github.com/kisom/aescrypt/strongbox"
+
+// Public keys may be encoded either as uncompressed SEC1 points, which
+// are PublicKeySize bytes long, or as compressed SEC1 points, which
+// are CompressedKeySize bytes long and carry only the X coordinate and
+// the parity of Y. Every function in this package that takes a public
+// key accepts either encoding.
+const (
+	PublicKeySize     = publicKeySize
+	CompressedKeySize = compressedKeySize
+)
+
+// BoxCompressed is the version byte that precedes the box type in
+// boxes sealed by a Sealer with the CompressKeys option. In these
+// boxes the ephemeral public key and the keys in the peer list are
+// compressed. Boxes without it use uncompressed keys.
+const BoxCompressed byte = 0x80
+
+// CompressedOverhead is the number of bytes of overhead when boxing a
+// message with compressed keys.
+var CompressedOverhead = compressedKeySize + strongbox.Overhead + 10
+
+const (
+	compressedKeySize = 67
+	coordSize         = 66
+)
+
+// CompressKey returns the compressed encoding of a public key. The key
+// may already be compressed.
+func CompressKey(pub PublicKey) (PublicKey, bool) {
+	pub, ok := decodeKey(pub)
+	if !ok {
+		return nil, false
+	}
+
+	out := make(PublicKey, compressedKeySize)
+	out[0] = 2 | pub[publicKeySize-1]&1
+	copy(out[1:], pub[1:1+coordSize])
+	return out, true
+}
+
+// DecompressKey returns the uncompressed encoding of a public key. The
+// key may already be uncompressed.
+func DecompressKey(pub PublicKey) (PublicKey, bool) {
+	return decodeKey(pub)
+}
+
+// decodeKey validates a public key in either encoding, and returns it
+// in the uncompressed encoding used for key agreement and signatures.
+func decodeKey(pub PublicKey) (PublicKey, bool) {
+	switch len(pub) {
+	case publicKeySize:
+		if _, err := ecdhCurve.NewPublicKey(pub); err != nil {
+			return nil, false
+		}
+		return pub, true
+	case compressedKeySize:
+		return decompressKey(pub)
+	default:
+		return nil, false
+	}
+}
+
+// encodeKey returns a public key in the encoding used by boxes with
+// the given version.
+func encodeKey(pub PublicKey, compressed bool) (PublicKey, bool) {
+	if compressed {
+		return CompressKey(pub)
+	}
+	return decodeKey(pub)
+}
+
+// boxHeader splits a box into its type and its body, and reports
+// whether the box uses compressed keys.
+func boxHeader(box []byte) (btype byte, body []byte, compressed bool, ok bool) {
+	if len(box) > 0 && box[0] == BoxCompressed {
+		box = box[1:]
+		compressed = true
+	}
+	if len(box) == 0 {
+		return 0, nil, false, false
+	}
+	return box[0], box[1:], compressed, true
+}
+
+// keySizeFor returns the size of the public keys in a box with the
+// given version.
+func keySizeFor(compressed bool) int {
+	if compressed {
+		return compressedKeySize
+	}
+	return publicKeySize
+}
@@ -0,0 +1,23 @@
+package stoutbox
+
+import "errors"
+
+// Errors returned by the error-returning variants of the package's
+// functions. They may be compared with errors.Is. ErrAuthFailed is
+// returned for any box that fails authentication: a box sealed for a
+// different key is deliberately indistinguishable from one that has
+// been modified.
+var (
+	ErrInvalidKey   = errors.New("stoutbox: invalid key")
+	ErrMalformed    = errors.New("stoutbox: malformed box")
+	ErrAuthFailed   = errors.New("stoutbox: message authentication failed")
+	ErrRandomness   = errors.New("stoutbox: failed to read random data")
+	ErrWrongType    = errors.New("stoutbox: wrong box type")
+	ErrNotRecipient = errors.New("stoutbox: not a recipient of the shared box")
+	ErrBadSignature = errors.New("stoutbox: invalid signature")
+	ErrSelfTest     = errors.New("stoutbox: self-test failed")
+
+	ErrReplay           = errors.New("stoutbox: replayed or out-of-order session message")
+	ErrCounterExhausted = errors.New("stoutbox: session message counter exhausted")
+	ErrTooFewShares     = errors.New("stoutbox: too few shares to open the threshold box")
+)
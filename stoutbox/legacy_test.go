@@ -0,0 +1,85 @@
+package stoutbox
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// The legacy boxes in testdata were produced by the implementation
+// built on crypto/elliptic and math/big, before the package moved to
+// crypto/ecdh. They must continue to open.
+
+func readLegacy(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile("testdata/legacy_" + name + ".bin")
+	if err != nil {
+		t.Fatalf("stoutbox: failed to read legacy vector %s: %v", name, err)
+	}
+	return b
+}
+
+func TestLegacyBoxes(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		box := readLegacy(t, fmt.Sprintf("box-%d", i+1))
+		message, ok := Open(box, testPeerKey)
+		if !ok {
+			t.Fatalf("stoutbox: failed to open legacy box %d", i+1)
+		} else if !bytes.Equal(message, []byte(testMessages[i])) {
+			t.Fatalf("stoutbox: legacy box %d does not match its message", i+1)
+		}
+	}
+
+	message, ok := OpenAndVerify(readLegacy(t, "signed"), testPeerKey, testGoodPub)
+	if !ok || !bytes.Equal(message, []byte(testMessages[0])) {
+		t.Fatal("stoutbox: failed to open legacy signed box")
+	}
+
+	shared := readLegacy(t, "shared")
+	for _, key := range []struct {
+		priv PrivateKey
+		pub  PublicKey
+	}{{testGoodKey, testGoodPub}, {testPeerKey, testPeerPub}} {
+		message, ok = OpenShared(shared, key.priv, key.pub)
+		if !ok || !bytes.Equal(message, []byte(testMessages[1])) {
+			t.Fatal("stoutbox: failed to open legacy shared box")
+		}
+	}
+
+	message, ok = OpenSharedAndVerify(readLegacy(t, "shared_signed"), testPeerKey, testPeerPub, testGoodPub)
+	if !ok || !bytes.Equal(message, []byte(testMessages[2])) {
+		t.Fatal("stoutbox: failed to open legacy signed shared box")
+	}
+
+	if !Verify([]byte(testMessages[4]), readLegacy(t, "signature"), testGoodPub) {
+		t.Fatal("stoutbox: failed to verify legacy signature")
+	} else if !VerifySignedKey(testPeerPub, testGoodPub, readLegacy(t, "signed_key")) {
+		t.Fatal("stoutbox: failed to verify legacy signed key")
+	}
+}
+
+// Named shared boxes are still sealed in the legacy format, with no
+// associated data, so that older versions can open them.
+func TestSharedBoxFormat(t *testing.T) {
+	peers := []PublicKey{testGoodPub, testPeerPub}
+	shared, ok := SealShared([]byte(testMessages[1]), peers)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal shared box")
+	}
+	signed, ok := SignAndSealShared([]byte(testMessages[2]), peers, testGoodKey, testGoodPub)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal signed shared box")
+	}
+
+	for _, box := range [][]byte{shared, signed} {
+		sb, err := parseSharedBox(box)
+		if err != nil {
+			t.Fatalf("stoutbox: failed to parse shared box: %v", err)
+		}
+
+		// contentKey opens the message without associated data.
+		if _, err = sb.contentKey(testPeerKey, testPeerPub); err != nil {
+			t.Fatalf("stoutbox: shared box type %d is not in the legacy format: %v", sb.btype, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package stoutbox
+
+import "github.com/kisom/aescrypt/padding"
+
+// PaddedOverhead returns the number of bytes of overhead when boxing
+// an n byte message with SealPadded using the scheme.
+func PaddedOverhead(n int, scheme padding.Scheme) int {
+	return Overhead + padding.Overhead(n, scheme)
+}
+
+// SealPadded pads the message according to the scheme before sealing
+// it, so that the box reveals only the padded length of the message.
+// The box must be opened with OpenPadded using the same scheme.
+func SealPadded(message []byte, peer PublicKey, scheme padding.Scheme) (box []byte, ok bool) {
+	padded := padding.Pad(message, scheme)
+	defer zero(padded)
+	return Seal(padded, peer)
+}
+
+// OpenPadded opens a box sealed with SealPadded, and verifies and
+// removes the padding.
+func OpenPadded(box []byte, key PrivateKey, scheme padding.Scheme) (message []byte, ok bool) {
+	padded, ok := Open(box, key)
+	if !ok {
+		return nil, false
+	}
+	return padding.Unpad(padded, scheme)
+}
+
+// SignAndSealPadded signs the message and pads the signed message
+// according to the scheme before sealing it, so that the box reveals
+// the length of neither the message nor its signature. The box must be
+// opened with OpenPaddedAndVerify using the same scheme.
+func SignAndSealPadded(message []byte, key PrivateKey, public PublicKey, peer PublicKey, scheme padding.Scheme) (box []byte, ok bool) {
+	signedMessage, err := defaultSealer.signMessage(message, key, public)
+	if err != nil {
+		return nil, false
+	}
+	defer zero(signedMessage)
+
+	padded := padding.Pad(signedMessage, scheme)
+	defer zero(padded)
+	box, err = defaultSealer.sealBox(padded, peer, BoxSigned)
+	return box, err == nil
+}
+
+// OpenPaddedAndVerify opens a box sealed with SignAndSealPadded,
+// removes the padding and verifies the signature.
+func OpenPaddedAndVerify(box []byte, key PrivateKey, peer PublicKey, scheme padding.Scheme) (message []byte, ok bool) {
+	btype, padded, err := openBox(box, key)
+	if err != nil {
+		return nil, false
+	}
+	defer zero(padded)
+	if btype != BoxSigned {
+		return nil, false
+	}
+
+	smessage, ok := padding.Unpad(padded, scheme)
+	if !ok {
+		return nil, false
+	}
+	message, err = verifyMessage(smessage, peer)
+	return message, err == nil
+}
+
+// SealSharedPadded pads the message according to the scheme before
+// sealing it in a shared box. The box must be opened with
+// OpenSharedPadded using the same scheme.
+func SealSharedPadded(message []byte, peers []PublicKey, scheme padding.Scheme) (box []byte, ok bool) {
+	padded := padding.Pad(message, scheme)
+	defer zero(padded)
+	return SealShared(padded, peers)
+}
+
+// OpenSharedPadded opens a shared box sealed with SealSharedPadded,
+// and verifies and removes the padding.
+func OpenSharedPadded(box []byte, key PrivateKey, public PublicKey, scheme padding.Scheme) (message []byte, ok bool) {
+	padded, ok := OpenShared(box, key, public)
+	if !ok {
+		return nil, false
+	}
+	return padding.Unpad(padded, scheme)
+}
+
+// SignAndSealSharedPadded signs the message and pads the signed
+// message according to the scheme before sealing it in a shared box.
+// The box must be opened with OpenSharedPaddedAndVerify using the same
+// scheme.
+func SignAndSealSharedPadded(message []byte, peers []PublicKey, sigkey PrivateKey, sigpub PublicKey, scheme padding.Scheme) (box []byte, ok bool) {
+	signedMessage, err := defaultSealer.signMessage(message, sigkey, sigpub)
+	if err != nil {
+		return nil, false
+	}
+	defer zero(signedMessage)
+
+	padded := padding.Pad(signedMessage, scheme)
+	defer zero(padded)
+	box, err = defaultSealer.buildSharedBox(padded, peers, BoxSharedSigned)
+	return box, err == nil
+}
+
+// OpenSharedPaddedAndVerify opens a shared box sealed with
+// SignAndSealSharedPadded, removes the padding and verifies the
+// signature.
+func OpenSharedPaddedAndVerify(box []byte, key PrivateKey, public PublicKey, signer PublicKey, scheme padding.Scheme) (message []byte, ok bool) {
+	btype, padded, err := unpackSharedBox(box, key, public)
+	if err != nil {
+		return nil, false
+	}
+	defer zero(padded)
+	if btype != BoxSharedSigned && btype != BoxSharedAnonymousSigned {
+		return nil, false
+	}
+
+	smessage, ok := padding.Unpad(padded, scheme)
+	if !ok {
+		return nil, false
+	}
+	message, err = verifyMessage(smessage, signer)
+	return message, err == nil
+}
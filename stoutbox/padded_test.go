@@ -0,0 +1,111 @@
+package stoutbox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kisom/aescrypt/padding"
+)
+
+func TestPaddedBoxing(t *testing.T) {
+	schemes := []padding.Scheme{padding.PADME, padding.Buckets(64, 256)}
+	for _, scheme := range schemes {
+		for i := 0; i < len(testMessages); i++ {
+			msg := []byte(testMessages[i])
+			box, ok := SealPadded(msg, testPeerPub, scheme)
+			if !ok {
+				t.Fatalf("stoutbox: padded boxing failed: message %d", i)
+			} else if len(box) != len(msg)+PaddedOverhead(len(msg), scheme) {
+				t.Fatal("stoutbox: the padded box length is invalid")
+			}
+
+			out, ok := OpenPadded(box, testPeerKey, scheme)
+			if !ok {
+				t.Fatalf("stoutbox: padded unboxing failed: message %d", i)
+			} else if !bytes.Equal(out, msg) {
+				t.Fatal("stoutbox: output message doesn't match original")
+			}
+
+			if _, ok = OpenPadded(box, testBadKey, scheme); ok {
+				t.Fatal("stoutbox: padded box opened with the wrong key")
+			}
+		}
+	}
+
+	short, ok := SealPadded([]byte("a"), testPeerPub, padding.Buckets(64))
+	if !ok {
+		t.Fatal("stoutbox: padded boxing failed")
+	}
+	long, ok := SealPadded(make([]byte, 60), testPeerPub, padding.Buckets(64))
+	if !ok {
+		t.Fatal("stoutbox: padded boxing failed")
+	} else if len(short) != len(long) {
+		t.Fatal("stoutbox: padding did not hide the message length")
+	}
+
+	// A box that isn't padded fails to open with OpenPadded.
+	box, ok := Seal(make([]byte, 64), testPeerPub)
+	if !ok {
+		t.Fatal("stoutbox: boxing failed")
+	} else if _, ok = OpenPadded(box, testPeerKey, padding.Buckets(64)); ok {
+		t.Fatal("stoutbox: unpadded box opened with OpenPadded")
+	}
+}
+
+// TestPaddedSignedAndShared ensures signed and shared boxes hide the
+// length of their messages when padded.
+func TestPaddedSignedAndShared(t *testing.T) {
+	scheme := padding.Buckets(256)
+	peers := []PublicKey{testGoodPub, testPeerPub}
+	short, long := []byte("a"), make([]byte, 100)
+
+	var boxes [2][]byte
+	for i, msg := range [][]byte{short, long} {
+		box, ok := SignAndSealPadded(msg, testGoodKey, testGoodPub, testPeerPub, scheme)
+		if !ok {
+			t.Fatal("stoutbox: padded signed boxing failed")
+		}
+		out, ok := OpenPaddedAndVerify(box, testPeerKey, testGoodPub, scheme)
+		if !ok || !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: padded signed unboxing failed")
+		} else if _, ok = OpenPaddedAndVerify(box, testPeerKey, testPeerPub, scheme); ok {
+			t.Fatal("stoutbox: padded signed box verified with the wrong signer")
+		}
+		boxes[i] = box
+	}
+	if len(boxes[0]) != len(boxes[1]) {
+		t.Fatal("stoutbox: padding did not hide the signed message length")
+	}
+
+	for i, msg := range [][]byte{short, long} {
+		box, ok := SealSharedPadded(msg, peers, scheme)
+		if !ok {
+			t.Fatal("stoutbox: padded shared boxing failed")
+		}
+		out, ok := OpenSharedPadded(box, testPeerKey, testPeerPub, scheme)
+		if !ok || !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: padded shared unboxing failed")
+		}
+		boxes[i] = box
+	}
+	if len(boxes[0]) != len(boxes[1]) {
+		t.Fatal("stoutbox: padding did not hide the shared message length")
+	}
+
+	for i, msg := range [][]byte{short, long} {
+		box, ok := SignAndSealSharedPadded(msg, peers, testGoodKey, testGoodPub, scheme)
+		if !ok {
+			t.Fatal("stoutbox: padded signed shared boxing failed")
+		}
+		out, ok := OpenSharedPaddedAndVerify(box, testPeerKey, testPeerPub, testGoodPub, scheme)
+		if !ok || !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: padded signed shared unboxing failed")
+		} else if _, ok = OpenSharedPadded(box, testPeerKey, testPeerPub, scheme); ok {
+			t.Fatal("stoutbox: padded signed shared box opened as unsigned")
+		}
+		boxes[i] = box
+	}
+	if len(boxes[0]) != len(boxes[1]) {
+		t.Fatal("stoutbox: padding did not hide the signed shared message length")
+	}
+}
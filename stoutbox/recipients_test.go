@@ -0,0 +1,130 @@
+package stoutbox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/kisom/aescrypt/strongbox"
+)
+
+func TestAddRecipients(t *testing.T) {
+	priv, pub, ok := GenerateKey()
+	if !ok {
+		t.Fatal("stoutbox: failed to generate key")
+	}
+	msg := []byte(testMessages[3])
+
+	box, ok := SignAndSealShared(msg, []PublicKey{testGoodPub}, testGoodKey, testGoodPub)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal shared box")
+	} else if _, err := OpenSharedE(box, priv, pub); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("stoutbox: peer should not yet be a recipient, got %v", err)
+	}
+
+	added, ok := AddRecipients(box, testGoodKey, testGoodPub, []PublicKey{pub, testGoodPub})
+	if !ok {
+		t.Fatal("stoutbox: failed to add recipients")
+	}
+
+	for _, key := range []struct {
+		priv PrivateKey
+		pub  PublicKey
+	}{{testGoodKey, testGoodPub}, {priv, pub}} {
+		out, ok := OpenSharedAndVerify(added, key.priv, key.pub, testGoodPub)
+		if !ok || !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: failed to open shared box after adding recipients")
+		}
+	}
+
+	sb, err := parseSharedBox(added)
+	if err != nil {
+		t.Fatalf("stoutbox: failed to parse shared box: %v", err)
+	} else if len(sb.peers) != 2 {
+		t.Fatalf("stoutbox: shared box should have 2 recipients, not %d", len(sb.peers))
+	}
+
+	if _, err = AddRecipientsE(box, priv, pub, []PublicKey{pub}); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("stoutbox: non-recipient added recipients, got %v", err)
+	}
+
+	anon, ok := SealSharedAnonymous(msg, []PublicKey{testGoodPub})
+	if !ok {
+		t.Fatal("stoutbox: failed to seal anonymous shared box")
+	} else if _, err = AddRecipientsE(anon, testGoodKey, testGoodPub, []PublicKey{pub}); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("stoutbox: added recipients to an anonymous shared box, got %v", err)
+	}
+
+	s := NewSealer(&Options{CompressKeys: true})
+	box, ok = s.SealShared(msg, []PublicKey{testGoodPub})
+	if !ok {
+		t.Fatal("stoutbox: failed to seal compressed shared box")
+	}
+	added, ok = AddRecipients(box, testGoodKey, testGoodPub, []PublicKey{pub})
+	if !ok {
+		t.Fatal("stoutbox: failed to add recipients to compressed shared box")
+	} else if added[0] != BoxCompressed {
+		t.Fatal("stoutbox: adding recipients should keep the box's key encoding")
+	} else if out, ok := OpenShared(added, priv, pub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("stoutbox: failed to open compressed shared box after adding recipients")
+	}
+}
+
+func TestRemoveRecipients(t *testing.T) {
+	priv, pub, ok := GenerateKey()
+	if !ok {
+		t.Fatal("stoutbox: failed to generate key")
+	}
+	msg := []byte(testMessages[4])
+	peers := []PublicKey{testGoodPub, testPeerPub, pub}
+
+	box, ok := SignAndSealShared(msg, peers, testGoodKey, testGoodPub)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal shared box")
+	}
+
+	removed, ok := RemoveRecipients(box, []PublicKey{pub})
+	if !ok {
+		t.Fatal("stoutbox: failed to remove recipients")
+	} else if _, err := OpenSharedAndVerifyE(removed, priv, pub, testGoodPub); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("stoutbox: removed recipient should not be listed, got %v", err)
+	} else if out, ok := OpenSharedAndVerify(removed, testPeerKey, testPeerPub, testGoodPub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("stoutbox: failed to open shared box after removing recipients")
+	}
+
+	rekeyed, ok := RemoveRecipientsAndRekey(box, testPeerKey, testPeerPub, []PublicKey{pub})
+	if !ok {
+		t.Fatal("stoutbox: failed to remove recipients and rekey")
+	} else if _, err := OpenSharedAndVerifyE(rekeyed, priv, pub, testGoodPub); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("stoutbox: removed recipient should not be listed, got %v", err)
+	}
+	for _, key := range []struct {
+		priv PrivateKey
+		pub  PublicKey
+	}{{testGoodKey, testGoodPub}, {testPeerKey, testPeerPub}} {
+		out, ok := OpenSharedAndVerify(rekeyed, key.priv, key.pub, testGoodPub)
+		if !ok || !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: failed to open shared box after rekeying")
+		}
+	}
+
+	// The old content key must not open the rekeyed box.
+	old, err := parseSharedBox(box)
+	if err != nil {
+		t.Fatalf("stoutbox: failed to parse shared box: %v", err)
+	}
+	shared, err := old.contentKey(priv, pub)
+	if err != nil {
+		t.Fatalf("stoutbox: failed to unwrap content key: %v", err)
+	}
+	sb, err := parseSharedBox(rekeyed)
+	if err != nil {
+		t.Fatalf("stoutbox: failed to parse shared box: %v", err)
+	} else if _, ok = strongbox.Open(sb.sbox, shared); ok {
+		t.Fatal("stoutbox: old content key opened the rekeyed box")
+	}
+
+	if _, err = RemoveRecipientsE(box, peers); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("stoutbox: removed every recipient, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package stoutbox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/kisom/aescrypt/gcmbox256"
+	"github.com/kisom/aescrypt/strongbox"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestSealer(t *testing.T) {
+	defer func(r, sr, gr io.Reader) {
+		PRNG, strongbox.PRNG, gcmbox256.PRNG = r, sr, gr
+	}(PRNG, strongbox.PRNG, gcmbox256.PRNG)
+	PRNG, strongbox.PRNG, gcmbox256.PRNG = failingReader{}, failingReader{}, failingReader{}
+
+	s := NewSealer(&Options{Rand: rand.Reader})
+	priv, pub, ok := s.GenerateKey()
+	if !ok {
+		t.Fatal("stoutbox: sealer failed to generate key")
+	}
+	msg := []byte(testMessages[0])
+
+	box, ok := s.Seal(msg, pub)
+	if !ok {
+		t.Fatal("stoutbox: sealer failed to seal message")
+	} else if out, ok := Open(box, priv); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("stoutbox: failed to open sealer box")
+	}
+
+	box, ok = s.SealGCM(msg, pub)
+	if !ok {
+		t.Fatal("stoutbox: sealer failed to seal GCM box")
+	} else if out, ok := Open(box, priv); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("stoutbox: failed to open sealer GCM box")
+	}
+
+	box, ok = s.SealShared(msg, []PublicKey{pub})
+	if !ok {
+		t.Fatal("stoutbox: sealer failed to seal shared box")
+	} else if out, ok := OpenShared(box, priv, pub); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("stoutbox: failed to open sealer shared box")
+	}
+
+	// The package-level functions use PRNG.
+	if _, _, ok = GenerateKey(); ok {
+		t.Fatal("stoutbox: GenerateKey should read from PRNG")
+	} else if _, ok = Seal(msg, pub); ok {
+		t.Fatal("stoutbox: Seal should read from PRNG")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if _, ok := s.SealShared(msg, []PublicKey{pub}); !ok {
+					t.Error("stoutbox: sealer failed to seal message")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSealerKeyRejection(t *testing.T) {
+	// Scalars of zero and of all ones are out of range and must be
+	// skipped in favour of the next scalar read.
+	invalid := append(make([]byte, privateKeySize), bytes.Repeat([]byte{0xff}, privateKeySize)...)
+	r := io.MultiReader(bytes.NewReader(invalid), bytes.NewReader(testGoodKey))
+	priv, pub, ok := NewSealer(&Options{Rand: r}).GenerateKey()
+	if !ok {
+		t.Fatal("stoutbox: sealer failed to generate key")
+	} else if !bytes.Equal(priv, testGoodKey) || !bytes.Equal(pub, testGoodPub) {
+		t.Fatal("stoutbox: sealer did not skip invalid scalars")
+	}
+
+	zeros := bytes.NewReader(make([]byte, maxKeyAttempts*privateKeySize))
+	if _, _, ok = NewSealer(&Options{Rand: zeros}).GenerateKey(); ok {
+		t.Fatal("stoutbox: sealer generated a key from invalid scalars")
+	}
+}
@@ -0,0 +1,91 @@
+package stoutbox
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+// Known-answer test vectors: two key pairs, the shared key they
+// agree on, and a signature over selfTestMessage by the first.
+var (
+	selfTestMessage = []byte("cryptobox power-on self-test")
+	selfTestPriv1   = "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20" +
+		"2122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f40" +
+		"4142"
+	selfTestPub1 = "04000366c8c3b22dfb87d0922163cd4b53cd43a24a29f79292fa4ef1288d69ed" +
+		"139a7fc0552120ea1bdb4f88ca0da4eb91de9b077018d5885dbff0e91a66639a" +
+		"9b72a500bd5e44e3a526e1051a4371c9bae5c7611ed489582ecdcc1ea277fe23" +
+		"79286a3a1c0c7224c7b1ebb0a8b6e5fbda5cead23f47c300917d4f98f2d2d4dc" +
+		"79d0109826"
+	selfTestPriv2 = "01808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e" +
+		"9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbe" +
+		"bfc0"
+	selfTestPub2 = "04006c21f6e1dc7ae20218b964dc6fd6bbfc6589f4f09692e264cb779f7d4592" +
+		"12bca79e6a1af02256f6027982a85ca8fcb552fd0658af9016154e837075571c" +
+		"500cec00ed3d4f3cd9e56d450e202844838494d73793491ddc7285ab8b73ac94" +
+		"4c606aab358f28c0942bb7399e8d849cfa0d1b6f8842579728aca4f26bc2cf62" +
+		"3ace800107"
+	selfTestShared = "e09aa62c903a9cc914c3d70d3fcb542b4ff124f513132f20d5d1170aba2396d3" +
+		"3b352de8957484c6886ec3cfcb3394c6ca7ed52c7a356a55fbcd62157ec3a232" +
+		"bc072d39412c08c47363f4f936f5d1b2"
+	selfTestSig = "0000004201d874885c08baa7e06eb6656d74947e4b1f7be62aafe756195512c9" +
+		"7a6dc1010726d466c33a0a00e1688722bc48bb505b988ef78769a3ecc891ef59" +
+		"537cc256c2340000004201b86d0031e949c6e38a1022e82c7fad5af2fa21d979" +
+		"16acf42d8ffb75f2bc81ceb2e800abe7f7aee9dde8b9d85e5f41465f57e7ffc6" +
+		"829021e5c6c537c4563caaa9"
+)
+
+// SelfTest runs known-answer tests of the ECDH key agreement and
+// signature verification behind the package, and a pairwise
+// consistency test of signing. If a test fails, it returns an error
+// wrapping ErrSelfTest, and every package in cryptobox refuses to
+// seal or open boxes from then on. Building with the
+// cryptobox_selftest tag runs SelfTest when the package is
+// initialised.
+func SelfTest() error {
+	if err := selfTest(); err != nil {
+		selftest.Fail()
+		return fmt.Errorf("%w: %v", ErrSelfTest, err)
+	}
+	return nil
+}
+
+func selfTest() error {
+	var vectors [6][]byte
+	for i, v := range []string{selfTestPriv1, selfTestPub1, selfTestPriv2, selfTestPub2, selfTestShared, selfTestSig} {
+		b, err := hex.DecodeString(v)
+		if err != nil {
+			return err
+		}
+		vectors[i] = b
+	}
+	priv1, pub1, priv2, pub2 := vectors[0], vectors[1], vectors[2], vectors[3]
+	shared, sig := vectors[4], vectors[5]
+
+	skey, ok := keyExchange(priv1, pub2)
+	if !ok || !bytes.Equal(skey, shared) {
+		return errors.New("ECDH known-answer test failed")
+	}
+	skey, ok = keyExchange(priv2, pub1)
+	if !ok || !bytes.Equal(skey, shared) {
+		return errors.New("ECDH known-answer test failed")
+	}
+
+	if err := VerifyE(selfTestMessage, sig, pub1); err != nil {
+		return errors.New("signature verification known-answer test failed")
+	} else if err = VerifyE(selfTestMessage, sig, pub2); err == nil {
+		return errors.New("signature verification accepted an invalid signature")
+	}
+
+	sig, err := SignE(selfTestMessage, priv1, pub1)
+	if err != nil {
+		return err
+	} else if err = VerifyE(selfTestMessage, sig, pub1); err != nil {
+		return errors.New("signature pairwise consistency test failed")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package stoutbox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/kisom/aescrypt/strongbox"
+)
+
+func testSessions(t testing.TB, opts *SessionOptions) (*Session, *Session) {
+	alice, ok := NewSession(testGoodKey, testPeerPub, opts)
+	if !ok {
+		t.Fatal("stoutbox: failed to create session")
+	}
+	bob, ok := NewSession(testPeerKey, testGoodPub, opts)
+	if !ok {
+		t.Fatal("stoutbox: failed to create session")
+	}
+	return alice, bob
+}
+
+func TestSession(t *testing.T) {
+	alice, bob := testSessions(t, nil)
+	for _, m := range testMessages {
+		msg := []byte(m)
+		box, ok := alice.Seal(msg)
+		if !ok {
+			t.Fatal("stoutbox: failed to seal session message")
+		} else if len(box) != len(msg)+alice.Overhead() {
+			t.Fatal("stoutbox: session box has the wrong overhead")
+		} else if out, ok := bob.Open(box); !ok || !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: failed to open session message")
+		}
+
+		// A message must not be accepted by its sender.
+		if _, err := alice.OpenE(box); !errors.Is(err, ErrAuthFailed) {
+			t.Fatalf("stoutbox: reflected session message should fail authentication, got %v", err)
+		}
+
+		box, ok = bob.Seal(msg)
+		if !ok {
+			t.Fatal("stoutbox: failed to seal session message")
+		} else if out, ok := alice.Open(box); !ok || !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: failed to open session message")
+		}
+	}
+
+	// The session keys are independent of the shared key.
+	shared, ok := SharedKey(testGoodKey, testPeerPub)
+	if !ok {
+		t.Fatal("stoutbox: failed to compute shared key")
+	}
+	sbox, ok := strongbox.Seal([]byte(testMessages[0]), shared)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal message")
+	} else if _, ok = bob.Open(sbox); ok {
+		t.Fatal("stoutbox: session opened a box sealed with the shared key")
+	}
+
+	alice.Destroy()
+	if _, ok = alice.Seal([]byte(testMessages[0])); ok {
+		t.Fatal("stoutbox: sealed with a destroyed session")
+	}
+
+	if _, ok = NewSession(testGoodKey, testPeerPub[1:], nil); ok {
+		t.Fatal("stoutbox: created a session with an invalid key")
+	}
+}
+
+func TestSessionCounter(t *testing.T) {
+	alice, bob := testSessions(t, &SessionOptions{Counter: true})
+	msg := []byte(testMessages[0])
+
+	var boxes [][]byte
+	for i := 0; i < 3; i++ {
+		box, ok := alice.Seal(msg)
+		if !ok {
+			t.Fatal("stoutbox: failed to seal session message")
+		} else if len(box) != len(msg)+alice.Overhead() {
+			t.Fatal("stoutbox: session box has the wrong overhead")
+		}
+		boxes = append(boxes, box)
+	}
+
+	if out, ok := bob.Open(boxes[0]); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("stoutbox: failed to open session message")
+	} else if _, err := bob.OpenE(boxes[0]); !errors.Is(err, ErrReplay) {
+		t.Fatalf("stoutbox: replayed session message should be rejected, got %v", err)
+	} else if _, ok = bob.Open(boxes[2]); !ok {
+		t.Fatal("stoutbox: failed to open session message")
+	} else if _, err = bob.OpenE(boxes[1]); !errors.Is(err, ErrReplay) {
+		t.Fatalf("stoutbox: reordered session message should be rejected, got %v", err)
+	}
+
+	// The counter is authenticated.
+	box, ok := alice.Seal(msg)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal session message")
+	}
+	box[counterSize-1]++
+	if _, err := bob.OpenE(box); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("stoutbox: session message with a modified counter should fail, got %v", err)
+	}
+
+	plain, _ := testSessions(t, nil)
+	box, ok = plain.Seal(msg)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal session message")
+	} else if _, ok = bob.Open(box); ok {
+		t.Fatal("stoutbox: counter session opened a message without a counter")
+	}
+}
+
+func BenchmarkSessionSeal(b *testing.B) {
+	alice, _ := testSessions(b, nil)
+	msg := []byte(testMessages[0])
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, ok := alice.Seal(msg); !ok {
+			b.Fatal("stoutbox: failed to seal session message")
+		}
+	}
+}
+
+func BenchmarkSessionOpen(b *testing.B) {
+	alice, bob := testSessions(b, nil)
+	box, ok := alice.Seal([]byte(testMessages[0]))
+	if !ok {
+		b.Fatal("stoutbox: failed to seal session message")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := bob.Open(box); !ok {
+			b.Fatal("stoutbox: failed to open session message")
+		}
+	}
+}
@@ -7,6 +7,8 @@ import "io/ioutil"
 import "math/big"
 import "testing"
 
+import "github.com/kisom/aescrypt/gcmbox256"
+
 var testMessages = []string{
 	"Hello, world.",
 	"Yes... yes. This is a fertile land, and we will thrive. We will rule over all this land, and we will call it... This Land.",
@@ -298,6 +300,44 @@ func TestLargerBox(t *testing.T) {
 	}
 }
 
+// TestGCMBoxing ensures boxes sealed with SealGCM open with Open.
+func TestGCMBoxing(t *testing.T) {
+	for i := 0; i < len(testMessages); i++ {
+		box, ok := SealGCM([]byte(testMessages[i]), testPeerPub)
+		if !ok {
+			t.Fatalf("stoutbox: GCM boxing failed: message %d", i)
+		} else if len(box) != len(testMessages[i])+GCMOverhead {
+			t.Fatal("stoutbox: the GCM box length is invalid")
+		} else if box[0] != BoxUnsignedGCM {
+			t.Fatal("stoutbox: the GCM box type is invalid")
+		}
+
+		message, ok := Open(box, testPeerKey)
+		if !ok {
+			t.Fatalf("stoutbox: GCM unboxing failed: message %d", i)
+		} else if string(message) != testMessages[i] {
+			t.Fatalf("stoutbox: GCM unboxing failed: expected '%s', got '%s'",
+				testMessages[i], string(message))
+		}
+
+		if _, ok = Open(box, testBadKey); ok {
+			t.Fatalf("stoutbox: GCM unboxing should have failed: message %d", i)
+		} else if _, ok = Open(mutate(box), testPeerKey); ok {
+			t.Fatalf("stoutbox: GCM unboxing should have failed: message %d", i)
+		}
+
+		// The GCM key is derived from the shared key, rather than
+		// reusing the secretbox encryption key.
+		r := newbr(box[1:])
+		shared, ok := SharedKey(testPeerKey, r.Next())
+		if !ok {
+			t.Fatal("stoutbox: failed to compute shared key")
+		} else if _, ok = gcmbox256.Open(r.Next(), gcmbox256.Key(shared[:gcmbox256.KeySize])); ok {
+			t.Fatal("stoutbox: GCM box was sealed with the secretbox key")
+		}
+	}
+}
+
 func BenchmarkUnsignedSeal(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, ok := Seal(testBoxFile, testPeerPub)
@@ -0,0 +1,98 @@
+package stoutbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestThresholdBoxes(t *testing.T) {
+	priv, pub, ok := GenerateKey()
+	if !ok {
+		t.Fatal("stoutbox: failed to generate key")
+	}
+	keys := []struct {
+		priv PrivateKey
+		pub  PublicKey
+	}{{testGoodKey, testGoodPub}, {testPeerKey, testPeerPub}, {priv, pub}}
+	peers := []PublicKey{testGoodPub, testPeerPub, pub}
+	msg := []byte(testMessages[5])
+
+	box, ok := SealThreshold(msg, peers, 2)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal threshold box")
+	} else if k, ok := BoxThresholdShares(box); !ok || k != 2 {
+		t.Fatal("stoutbox: threshold box should need 2 shares")
+	} else if _, ok = OpenShared(box, testGoodKey, testGoodPub); ok {
+		t.Fatal("stoutbox: a single peer opened a threshold box")
+	}
+
+	var shares [][]byte
+	for _, key := range keys {
+		share, ok := OpenThresholdShare(box, key.priv, key.pub)
+		if !ok {
+			t.Fatal("stoutbox: failed to open threshold share")
+		}
+		shares = append(shares, share)
+	}
+
+	for _, chosen := range [][][]byte{shares[:2], shares[1:], {shares[2], shares[0]}, shares} {
+		out, ok := CombineThreshold(box, chosen)
+		if !ok {
+			t.Fatal("stoutbox: failed to combine threshold shares")
+		} else if !bytes.Equal(out, msg) {
+			t.Fatal("stoutbox: threshold box did not round trip")
+		}
+	}
+
+	if _, err := CombineThresholdE(box, shares[:1]); !errors.Is(err, ErrTooFewShares) {
+		t.Fatalf("stoutbox: combined too few shares, got %v", err)
+	}
+
+	bad := [][]byte{shares[0], append([]byte{}, shares[1]...)}
+	bad[1][1] ^= 1
+	if _, err := CombineThresholdE(box, bad); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("stoutbox: combined a modified share, got %v", err)
+	}
+
+	// The threshold follows the type byte, the ephemeral key and the
+	// threshold's own length.
+	raised := append([]byte{}, box...)
+	binary.BigEndian.PutUint32(raised[1+4+PublicKeySize+4:], 3)
+	if _, err := CombineThresholdE(raised, shares); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("stoutbox: combined shares for a modified threshold, got %v", err)
+	}
+
+	_, other, ok := GenerateKey()
+	if !ok {
+		t.Fatal("stoutbox: failed to generate key")
+	} else if _, err := OpenThresholdShareE(box, priv, other); !errors.Is(err, ErrNotRecipient) {
+		t.Fatalf("stoutbox: non-peer opened a threshold share, got %v", err)
+	}
+
+	for _, k := range []int{0, 4} {
+		if _, err := SealThresholdE(msg, peers, k); !errors.Is(err, ErrTooFewShares) {
+			t.Fatalf("stoutbox: sealed a threshold box needing %d of 3 shares, got %v", k, err)
+		}
+	}
+
+	s := NewSealer(&Options{CompressKeys: true})
+	box, ok = s.SealThreshold(msg, peers, 3)
+	if !ok {
+		t.Fatal("stoutbox: failed to seal compressed threshold box")
+	}
+	shares = nil
+	for _, key := range keys {
+		share, ok := OpenThresholdShare(box, key.priv, key.pub)
+		if !ok {
+			t.Fatal("stoutbox: failed to open compressed threshold share")
+		}
+		shares = append(shares, share)
+	}
+	if out, ok := CombineThreshold(box, shares); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("stoutbox: failed to open compressed threshold box")
+	} else if _, err := CombineThresholdE(box, shares[:2]); !errors.Is(err, ErrTooFewShares) {
+		t.Fatalf("stoutbox: combined too few shares, got %v", err)
+	}
+}
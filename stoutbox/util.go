@@ -96,6 +96,11 @@ func (b *br) Next() []byte {
 	return nil
 }
 
+// Len returns the number of unread bytes.
+func (b *br) Len() int {
+	return b.buf.Len()
+}
+
 func (b *br) NextU32() (uint32, bool) {
 	if b.err != nil {
 		return 0, false
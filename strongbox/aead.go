@@ -0,0 +1,107 @@
+package strongbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+type aead struct {
+	block  cipher.Block
+	tagKey []byte
+}
+
+// NewAEAD returns a cipher.AEAD that uses the same AES-256-CTR and
+// HMAC-SHA-384 encrypt-then-MAC construction as Seal, and a boolean
+// indicating success. The nonce is used as the CTR IV and must be
+// NonceSize bytes long; it must never be repeated under the same key.
+// The ciphertext produced by Seal is the box that SealWithAD would
+// produce for the same nonce, without the leading nonce.
+func NewAEAD(key Key) (cipher.AEAD, bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	}
+
+	block, err := newBlock(key[:cryptKeySize])
+	if err != nil {
+		return nil, false
+	}
+
+	tagKey := make([]byte, tagKeySize)
+	copy(tagKey, key[cryptKeySize:])
+	return &aead{block: block, tagKey: tagKey}, true
+}
+
+// NonceSize returns the size of the nonce that must be passed to Seal
+// and Open.
+func (a *aead) NonceSize() int {
+	return aes.BlockSize
+}
+
+// Overhead returns the difference between the lengths of a plaintext
+// and its ciphertext.
+func (a *aead) Overhead() int {
+	return sha512.Size384
+}
+
+// Seal encrypts and authenticates plaintext, authenticates the
+// additional data and appends the result to dst, returning the updated
+// slice. cipher.AEAD's Seal cannot return an error, so it panics with
+// ErrSelfTest if the self-test has failed.
+func (a *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != aes.BlockSize {
+		panic("strongbox: incorrect nonce length given to AEAD")
+	} else if selftest.Failed() {
+		panic(ErrSelfTest)
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+sha512.Size384)
+	ct := out[:len(plaintext)]
+	cipher.NewCTR(a.block, nonce).XORKeyStream(ct, plaintext)
+
+	tag := computeTag(a.tagKey, additionalData, nonce, ct)
+	copy(out[len(plaintext):], tag)
+	return ret
+}
+
+// Open authenticates and decrypts ciphertext, authenticates the
+// additional data and, if successful, appends the resulting plaintext
+// to dst, returning the updated slice.
+func (a *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != aes.BlockSize {
+		panic("strongbox: incorrect nonce length given to AEAD")
+	}
+
+	if selftest.Failed() {
+		return nil, ErrSelfTest
+	} else if len(ciphertext) < sha512.Size384 {
+		return nil, ErrMalformed
+	}
+
+	ctLen := len(ciphertext) - sha512.Size384
+	ct := ciphertext[:ctLen]
+	tag := computeTag(a.tagKey, additionalData, nonce, ct)
+	if subtle.ConstantTimeCompare(tag, ciphertext[ctLen:]) != 1 {
+		return nil, ErrAuthFailed
+	}
+
+	ret, out := sliceForAppend(dst, ctLen)
+	cipher.NewCTR(a.block, nonce).XORKeyStream(out, ct)
+	return ret, nil
+}
+
+// sliceForAppend extends in by n bytes, returning the extended slice
+// and the newly added tail.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,111 @@
+package strongbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/subtle"
+	"hash"
+	"io"
+
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+// A Cipher seals and opens boxes under a single key. It caches the
+// AES block cipher and HMAC state for the key, and appends its output
+// to a caller-supplied buffer, so that sealing many messages under
+// the same key does not allocate for each one. Boxes produced by a
+// Cipher are identical in format to those produced by Seal. A Cipher
+// is not safe for concurrent use.
+type Cipher struct {
+	sealer *Sealer
+	block  cipher.Block
+	mac    hash.Hash
+	tag    [sha512.Size384]byte
+}
+
+// NewCipher returns a Cipher for the key, and a boolean indicating
+// success.
+func NewCipher(key Key) (*Cipher, bool) {
+	return defaultSealer.NewCipher(key)
+}
+
+func newCipher(s *Sealer, key Key) (*Cipher, bool) {
+	if !KeyIsSuitable(key) {
+		return nil, false
+	}
+
+	block, err := newBlock(key[:cryptKeySize])
+	if err != nil {
+		return nil, false
+	}
+	return &Cipher{sealer: s, block: block, mac: hmac.New(sha512.New384, key[cryptKeySize:])}, true
+}
+
+// SealTo seals the message and appends the box to dst, returning the
+// updated slice. If dst has room for Overhead more bytes than the
+// message, no allocation is made for the box. dst and message must
+// not overlap.
+func (c *Cipher) SealTo(dst, message []byte) (box []byte, ok bool) {
+	if selftest.Failed() {
+		return nil, false
+	}
+
+	ret, out := sliceForAppend(dst, len(message)+Overhead)
+	iv := out[:aes.BlockSize]
+	if _, err := io.ReadFull(c.sealer.reader(), iv); err != nil {
+		return nil, false
+	}
+
+	ctLen := aes.BlockSize + len(message)
+	cipher.NewCTR(c.block, iv).XORKeyStream(out[aes.BlockSize:ctLen], message)
+
+	c.mac.Reset()
+	c.mac.Write(out[:ctLen])
+	c.mac.Sum(out[ctLen:ctLen])
+	return ret, true
+}
+
+// OpenTo authenticates and decrypts the box, and appends the message
+// to dst, returning the updated slice. If dst has room for the
+// message, no allocation is made for it. dst and box must not
+// overlap.
+func (c *Cipher) OpenTo(dst, box []byte) (message []byte, ok bool) {
+	if selftest.Failed() {
+		return nil, false
+	} else if len(box) < Overhead {
+		return nil, false
+	}
+
+	ctLen := len(box) - sha512.Size384
+	c.mac.Reset()
+	c.mac.Write(box[:ctLen])
+	if subtle.ConstantTimeCompare(c.mac.Sum(c.tag[:0]), box[ctLen:]) != 1 {
+		return nil, false
+	}
+
+	ret, out := sliceForAppend(dst, ctLen-aes.BlockSize)
+	cipher.NewCTR(c.block, box[:aes.BlockSize]).XORKeyStream(out, box[aes.BlockSize:ctLen])
+	return ret, true
+}
+
+// SealTo seals the message as Seal does, appending the box to dst.
+// Callers sealing many messages under the same key should use a
+// Cipher, which avoids setting up the key for each message.
+func SealTo(dst, message []byte, key Key) (box []byte, ok bool) {
+	c, ok := NewCipher(key)
+	if !ok {
+		return nil, false
+	}
+	return c.SealTo(dst, message)
+}
+
+// OpenTo opens the box as Open does, appending the message to dst.
+func OpenTo(dst, box []byte, key Key) (message []byte, ok bool) {
+	c, ok := NewCipher(key)
+	if !ok {
+		return nil, false
+	}
+	return c.OpenTo(dst, box)
+}
@@ -0,0 +1,101 @@
+package strongbox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCipher(t *testing.T) {
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		t.Fatal("strongbox: failed to create cipher")
+	}
+
+	prefix := []byte("prefix")
+	for i := 0; i < len(testMessages); i++ {
+		msg := []byte(testMessages[i])
+		dst := make([]byte, len(prefix), len(prefix)+len(msg)+Overhead)
+		copy(dst, prefix)
+
+		box, ok := c.SealTo(dst, msg)
+		if !ok {
+			t.Fatalf("strongbox: cipher failed to seal message %d", i)
+		} else if &box[0] != &dst[0] {
+			t.Fatal("strongbox: SealTo did not use the destination buffer")
+		} else if !bytes.Equal(box[:len(prefix)], prefix) {
+			t.Fatal("strongbox: SealTo overwrote the destination")
+		}
+
+		// Boxes from a Cipher and from Seal are interchangeable.
+		box = box[len(prefix):]
+		if out, ok := Open(box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+			t.Fatalf("strongbox: Open failed on cipher box %d", i)
+		}
+
+		sbox, ok := Seal(msg, testGoodKey)
+		if !ok {
+			t.Fatalf("strongbox: failed to seal message %d", i)
+		}
+		out, ok := c.OpenTo(prefix, sbox)
+		if !ok {
+			t.Fatalf("strongbox: cipher failed to open box %d", i)
+		} else if !bytes.Equal(out, append(append([]byte{}, prefix...), msg...)) {
+			t.Fatal("strongbox: OpenTo output doesn't match original")
+		}
+
+		if out, ok = OpenTo(nil, box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+			t.Fatalf("strongbox: OpenTo failed on box %d", i)
+		} else if _, ok = OpenTo(nil, box, testBadKey); ok {
+			t.Fatal("strongbox: OpenTo opened a box with the wrong key")
+		}
+
+		box[len(box)-1] ^= 1
+		if _, ok = c.OpenTo(nil, box); ok {
+			t.Fatal("strongbox: cipher opened a modified box")
+		}
+	}
+
+	if _, ok = c.OpenTo(nil, make([]byte, Overhead-1)); ok {
+		t.Fatal("strongbox: cipher opened a short box")
+	} else if _, ok = NewCipher(testGoodKey[1:]); ok {
+		t.Fatal("strongbox: created a cipher with an invalid key")
+	} else if _, ok = SealTo(nil, testBoxFile, testGoodKey[1:]); ok {
+		t.Fatal("strongbox: SealTo sealed with an invalid key")
+	}
+}
+
+// Benchmark sealing with a Cipher into a reused buffer.
+func BenchmarkCipherSeal(b *testing.B) {
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		b.Fatal("strongbox: failed to create cipher")
+	}
+	buf := make([]byte, 0, len(testBoxFile)+Overhead)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok = c.SealTo(buf, testBoxFile); !ok {
+			b.Fatal("strongbox: couldn't seal message")
+		}
+	}
+}
+
+// Benchmark opening with a Cipher into a reused buffer.
+func BenchmarkCipherOpen(b *testing.B) {
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		b.Fatal("strongbox: failed to create cipher")
+	}
+	box, ok := Seal(testBoxFile, testGoodKey)
+	if !ok {
+		b.Fatal("strongbox: couldn't seal message")
+	}
+	buf := make([]byte, 0, len(testBoxFile))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok = c.OpenTo(buf, box); !ok {
+			b.Fatal("strongbox: couldn't open message")
+		}
+	}
+}
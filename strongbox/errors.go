@@ -0,0 +1,16 @@
+package strongbox
+
+import "errors"
+
+// Errors returned by the error-returning variants of the package's
+// functions. They may be compared with errors.Is. ErrAuthFailed is
+// returned for any box that fails authentication: a box sealed under
+// a different key is deliberately indistinguishable from one that has
+// been modified.
+var (
+	ErrInvalidKey = errors.New("strongbox: invalid key")
+	ErrMalformed  = errors.New("strongbox: malformed box")
+	ErrAuthFailed = errors.New("strongbox: message authentication failed")
+	ErrRandomness = errors.New("strongbox: failed to read random data")
+	ErrSelfTest   = errors.New("strongbox: self-test failed")
+)
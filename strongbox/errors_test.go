@@ -0,0 +1,40 @@
+package strongbox
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	key, ok := GenerateKey()
+	if !ok {
+		t.Fatal("strongbox: failed to generate key")
+	}
+	other, ok := GenerateKey()
+	if !ok {
+		t.Fatal("strongbox: failed to generate key")
+	}
+
+	box, err := SealE([]byte(testMessages[0]), key)
+	if err != nil {
+		t.Fatalf("strongbox: failed to seal message: %v", err)
+	}
+
+	if _, err = SealE([]byte(testMessages[0]), key[:KeySize-1]); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("strongbox: expected ErrInvalidKey, got %v", err)
+	} else if _, err = OpenE(box, key[:KeySize-1]); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("strongbox: expected ErrInvalidKey, got %v", err)
+	} else if _, err = OpenE(box[:Overhead-1], key); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("strongbox: expected ErrMalformed, got %v", err)
+	}
+
+	// A box opened with the wrong key must be indistinguishable from
+	// one that has been tampered with.
+	if _, err = OpenE(box, other); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("strongbox: expected ErrAuthFailed, got %v", err)
+	}
+	box[0] ^= 1
+	if _, err = OpenE(box, key); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("strongbox: expected ErrAuthFailed, got %v", err)
+	}
+}
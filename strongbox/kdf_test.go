@@ -0,0 +1,92 @@
+package strongbox
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("strongbox: invalid test vector: %v", err)
+	}
+	return b
+}
+
+// Test cases 1 and 3 from RFC 5869, appendix A, followed by the same
+// inputs with HMAC-SHA-384. RFC 5869 does not give SHA-384 vectors;
+// these were computed with OpenSSL's HKDF.
+func TestHKDFVectors(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	salt := unhex(t, "000102030405060708090a0b0c")
+	info := unhex(t, "f0f1f2f3f4f5f6f7f8f9")
+
+	vectors := []struct {
+		sha384     bool
+		salt, info []byte
+		okm        string
+	}{
+		{false, salt, info, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"},
+		{false, nil, nil, "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"},
+		{true, salt, info, "9b5097a86038b805309076a44b3a9f38063e25b516dcbf369f394cfab43685f748b6457763e4f0204fc5"},
+		{true, nil, nil, "c8c96e710f89b0d7990bca68bcdec8cf854062e54c73a7abc743fade9b242daacc1cea5670415b52849c"},
+	}
+
+	for i, v := range vectors {
+		h := sha256.New
+		if v.sha384 {
+			h = sha512.New384
+		}
+
+		okm, ok := hkdfKey(h, ikm, v.salt, v.info, 42)
+		if !ok {
+			t.Fatalf("strongbox: HKDF failed on vector %d", i)
+		} else if !bytes.Equal(okm, unhex(t, v.okm)) {
+			t.Fatalf("strongbox: HKDF vector %d failed", i)
+		}
+	}
+}
+
+// The known answer for DeriveKey was checked against OpenSSL's HKDF
+// with SHA-384, no salt, and the info "sessions\x00tenant-1".
+func TestDeriveKey(t *testing.T) {
+	master := make(Key, KeySize)
+	for i := range master {
+		master[i] = byte(i)
+	}
+
+	expected := unhex(t, "610db9e04d7ceae85a61ce339c426a91a4ebfcb993bd75c93cf1eb5a33c18073dcf54357f17e608912ea28255fee39a02acb395aa12848a516a78fd521e0f70b2a6ee53866be98b7dd13ba38bc0d3477")
+	key, ok := DeriveKey(master, []byte("tenant-1"), []byte("sessions"))
+	if !ok {
+		t.Fatal("strongbox: failed to derive key")
+	} else if !bytes.Equal(key, expected) {
+		t.Fatal("strongbox: derived key does not match known answer")
+	} else if !KeyIsSuitable(key) {
+		t.Fatal("strongbox: derived key is not suitable")
+	}
+
+	other, ok := DeriveKey(master, []byte("tenant-1"), []byte("exports"))
+	if !ok {
+		t.Fatal("strongbox: failed to derive key")
+	} else if bytes.Equal(key, other) {
+		t.Fatal("strongbox: keys with different labels should differ")
+	}
+
+	other, ok = DeriveKey(master, []byte("tenant-2"), []byte("sessions"))
+	if !ok {
+		t.Fatal("strongbox: failed to derive key")
+	} else if bytes.Equal(key, other) {
+		t.Fatal("strongbox: keys with different contexts should differ")
+	}
+
+	if _, ok = DeriveKey(master[1:], nil, []byte("sessions")); ok {
+		t.Fatal("strongbox: derived a key from an invalid master key")
+	} else if _, ok = DeriveKey(master, nil, nil); ok {
+		t.Fatal("strongbox: derived a key with an empty label")
+	} else if _, ok = DeriveKey(master, nil, []byte("sessions\x00")); ok {
+		t.Fatal("strongbox: derived a key with a label containing a zero byte")
+	}
+}
@@ -0,0 +1,53 @@
+package strongbox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kisom/aescrypt/padding"
+)
+
+func TestPaddedBoxing(t *testing.T) {
+	schemes := []padding.Scheme{padding.PADME, padding.Buckets(64, 256)}
+	for _, scheme := range schemes {
+		for i := 0; i < len(testMessages); i++ {
+			msg := []byte(testMessages[i])
+			box, ok := SealPadded(msg, testGoodKey, scheme)
+			if !ok {
+				t.Fatalf("strongbox: padded boxing failed: message %d", i)
+			} else if len(box) != len(msg)+PaddedOverhead(len(msg), scheme) {
+				t.Fatal("strongbox: the padded box length is invalid")
+			}
+
+			out, ok := OpenPadded(box, testGoodKey, scheme)
+			if !ok {
+				t.Fatalf("strongbox: padded unboxing failed: message %d", i)
+			} else if !bytes.Equal(out, msg) {
+				t.Fatal("strongbox: output message doesn't match original")
+			}
+
+			if _, ok = OpenPadded(box, testBadKey, scheme); ok {
+				t.Fatal("strongbox: padded box opened with the wrong key")
+			}
+		}
+	}
+
+	short, ok := SealPadded([]byte("a"), testGoodKey, padding.Buckets(64))
+	if !ok {
+		t.Fatal("strongbox: padded boxing failed")
+	}
+	long, ok := SealPadded(make([]byte, 60), testGoodKey, padding.Buckets(64))
+	if !ok {
+		t.Fatal("strongbox: padded boxing failed")
+	} else if len(short) != len(long) {
+		t.Fatal("strongbox: padding did not hide the message length")
+	}
+
+	// A box that isn't padded fails to open with OpenPadded.
+	box, ok := Seal(make([]byte, 64), testGoodKey)
+	if !ok {
+		t.Fatal("strongbox: boxing failed")
+	} else if _, ok = OpenPadded(box, testGoodKey, padding.Buckets(64)); ok {
+		t.Fatal("strongbox: unpadded box opened with OpenPadded")
+	}
+}
@@ -0,0 +1,151 @@
+package strongbox
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math"
+)
+
+// A passphrase box is a header describing how the key was derived,
+// followed by a box sealed under the derived key with the header as
+// associated data:
+//
+//	version (1 byte) || hash (1 byte) || iterations (4 bytes) ||
+//	salt length (1 byte) || salt || box
+const (
+	passphraseVersion    = 1
+	passphraseHeaderSize = 7
+	saltSize             = 16
+
+	// minIterations is the smallest iteration count SP 800-132
+	// recommends. OpenWithPassphrase refuses boxes asking for more
+	// than maxIterationFactor times PassphraseIterations, which
+	// bounds the work an attacker can force with a forged header.
+	minIterations      = 1000
+	maxIterationFactor = 4
+)
+
+// Identifiers for the PBKDF2 pseudorandom function in a passphrase
+// box header.
+const (
+	hashSHA256 byte = 1
+	hashSHA384 byte = 2
+	hashSHA512 byte = 3
+)
+
+// PassphraseIterations is the number of PBKDF2 iterations used by
+// SealWithPassphrase. It may be raised over time; boxes record the
+// count they were sealed with.
+var PassphraseIterations = 600000
+
+func passphraseHash(id byte) func() hash.Hash {
+	switch id {
+	case hashSHA256:
+		return sha256.New
+	case hashSHA384:
+		return sha512.New384
+	case hashSHA512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+func deriveKey(h func() hash.Hash, passphrase, salt []byte, iterations int) (Key, bool) {
+	if h == nil || len(salt) < saltSize {
+		return nil, false
+	} else if iterations < minIterations {
+		return nil, false
+	}
+
+	key, err := pbkdf2.Key(h, string(passphrase), salt, iterations, KeySize)
+	if err != nil {
+		return nil, false
+	}
+	return key, true
+}
+
+// DeriveKeyFromPassphrase derives a key from a passphrase using
+// PBKDF2 with HMAC-SHA-384, as described in NIST SP 800-132. The salt
+// should be randomly generated and must be at least 16 bytes long,
+// and iterations must be at least 1000.
+func DeriveKeyFromPassphrase(passphrase, salt []byte, iterations int) (Key, bool) {
+	return deriveKey(sha512.New384, passphrase, salt, iterations)
+}
+
+// SealWithPassphrase seals a message under a key derived from the
+// passphrase. A header recording the salt, iteration count and hash
+// used is stored in front of the box, so that it can be opened with
+// OpenWithPassphrase even if PassphraseIterations changes. It fails if
+// PassphraseIterations does not fit in the header's 32 bits.
+func SealWithPassphrase(message, passphrase []byte) (box []byte, ok bool) {
+	return defaultSealer.SealWithPassphrase(message, passphrase)
+}
+
+func sealWithPassphrase(r io.Reader, message, passphrase []byte) (box []byte, ok bool) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, false
+	}
+
+	// The header records the iteration count in four bytes.
+	iterations := PassphraseIterations
+	if uint64(iterations) > math.MaxUint32 {
+		return nil, false
+	}
+
+	key, ok := DeriveKeyFromPassphrase(passphrase, salt, iterations)
+	if !ok {
+		return nil, false
+	}
+	defer zero(key)
+
+	hdr := make([]byte, passphraseHeaderSize, passphraseHeaderSize+saltSize)
+	hdr[0] = passphraseVersion
+	hdr[1] = hashSHA384
+	binary.BigEndian.PutUint32(hdr[2:], uint32(iterations))
+	hdr[6] = saltSize
+	hdr = append(hdr, salt...)
+
+	sbox, err := sealWithAD(r, message, hdr, key)
+	if err != nil {
+		return nil, false
+	}
+	return append(hdr, sbox...), true
+}
+
+// OpenWithPassphrase opens a box sealed with SealWithPassphrase. The
+// iteration count is read from the box before it can be authenticated,
+// so boxes recording more than four times PassphraseIterations are
+// rejected without deriving a key.
+func OpenWithPassphrase(box, passphrase []byte) (message []byte, ok bool) {
+	if len(box) < passphraseHeaderSize {
+		return nil, false
+	} else if box[0] != passphraseVersion {
+		return nil, false
+	}
+
+	hdrLen := passphraseHeaderSize + int(box[6])
+	if len(box) < hdrLen+Overhead {
+		return nil, false
+	}
+	hdr := box[:hdrLen]
+	iterations := binary.BigEndian.Uint32(hdr[2:])
+	limit := maxIterationFactor * uint64(max(PassphraseIterations, minIterations))
+	if uint64(iterations) > limit {
+		return nil, false
+	}
+
+	key, ok := deriveKey(passphraseHash(hdr[1]), passphrase,
+		hdr[passphraseHeaderSize:], int(iterations))
+	if !ok {
+		return nil, false
+	}
+	defer zero(key)
+
+	return OpenWithAD(box[hdrLen:], hdr, key)
+}
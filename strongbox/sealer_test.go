@@ -0,0 +1,98 @@
+package strongbox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"sync"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestSealer(t *testing.T) {
+	defer func(r io.Reader) { PRNG = r }(PRNG)
+	PRNG = failingReader{}
+
+	iv := bytes.Repeat([]byte{0x42}, 16)
+	s := NewSealer(&Options{Rand: bytes.NewReader(iv)})
+	msg := []byte(testMessages[0])
+
+	box, ok := s.Seal(msg, testGoodKey)
+	if !ok {
+		t.Fatal("strongbox: sealer failed to seal message")
+	} else if !bytes.Equal(box[:len(iv)], iv) {
+		t.Fatal("strongbox: sealer did not use its source of random data")
+	} else if out, ok := Open(box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("strongbox: failed to open sealer box")
+	}
+
+	// The package-level functions use PRNG.
+	if _, ok = Seal(msg, testGoodKey); ok {
+		t.Fatal("strongbox: Seal should read from PRNG")
+	} else if _, ok = GenerateKey(); ok {
+		t.Fatal("strongbox: GenerateKey should read from PRNG")
+	}
+
+	// So do ciphers, streams, passphrase boxes and guarded keys,
+	// unless they come from a Sealer.
+	defer func(n int) { PassphraseIterations = n }(PassphraseIterations)
+	PassphraseIterations = minIterations
+
+	c, ok := NewCipher(testGoodKey)
+	if !ok {
+		t.Fatal("strongbox: failed to create cipher")
+	} else if _, ok = c.SealTo(nil, msg); ok {
+		t.Fatal("strongbox: Cipher should read from PRNG")
+	} else if _, ok = SealWithPassphrase(msg, testPassphrase); ok {
+		t.Fatal("strongbox: SealWithPassphrase should read from PRNG")
+	} else if _, ok = GenerateGuardedKey(); ok {
+		t.Fatal("strongbox: GenerateGuardedKey should read from PRNG")
+	}
+
+	good := NewSealer(&Options{Rand: rand.Reader})
+	if c, ok = good.NewCipher(testGoodKey); !ok {
+		t.Fatal("strongbox: sealer failed to create cipher")
+	} else if box, ok = c.SealTo(nil, msg); !ok {
+		t.Fatal("strongbox: sealer cipher failed to seal message")
+	} else if out, ok := Open(box, testGoodKey); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("strongbox: failed to open sealer cipher box")
+	}
+
+	if box, ok = good.SealWithPassphrase(msg, testPassphrase); !ok {
+		t.Fatal("strongbox: sealer failed to seal with passphrase")
+	} else if out, ok := OpenWithPassphrase(box, testPassphrase); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("strongbox: failed to open sealer passphrase box")
+	}
+
+	gkey, ok := good.GenerateGuardedKey()
+	if !ok {
+		t.Fatal("strongbox: sealer failed to generate guarded key")
+	}
+	gkey.Destroy()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := NewSealer(&Options{Rand: rand.Reader})
+			key, ok := s.GenerateKey()
+			if !ok {
+				t.Error("strongbox: sealer failed to generate key")
+				return
+			}
+			for j := 0; j < 16; j++ {
+				if _, ok = s.SealWithAD(msg, msg, key); !ok {
+					t.Error("strongbox: sealer failed to seal message")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,66 @@
+package strongbox
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/kisom/aescrypt/internal/selftest"
+)
+
+// The known-answer test seals selfTestMessage under a key made up of
+// the bytes 0, 1, ..., KeySize-1 with the IV 0x80, 0x81, ..., 0x8f.
+var (
+	selfTestMessage = []byte("cryptobox power-on self-test")
+	selfTestBox     = "808182838485868788898a8b8c8d8e8fd3c28c0bbff8d5ecd973ff98f9ff1aef91eb93110b001af911cbd5bae3b146d859d8d7b3a6b9ced7d6610d27950ba4027f13f5b6f426efe8ac4b8247511328233f15f68b13475017617e473a"
+)
+
+// SelfTest runs known-answer tests of the encryption, decryption and
+// tag computation behind Seal and Open. If a test fails, it returns
+// an error wrapping ErrSelfTest, and every package in cryptobox
+// refuses to seal or open boxes from then on. Building with the
+// cryptobox_selftest tag runs SelfTest when the package is
+// initialised.
+func SelfTest() error {
+	if err := selfTest(); err != nil {
+		selftest.Fail()
+		return fmt.Errorf("%w: %v", ErrSelfTest, err)
+	}
+	return nil
+}
+
+func selfTest() error {
+	key := make([]byte, KeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	expected, err := hex.DecodeString(selfTestBox)
+	if err != nil {
+		return err
+	}
+	iv := expected[:aes.BlockSize]
+	tagStart := len(expected) - sha512.Size384
+
+	ct, err := encrypt(bytes.NewReader(iv), key[:cryptKeySize], selfTestMessage)
+	if err != nil {
+		return err
+	} else if !bytes.Equal(ct, expected[:tagStart]) {
+		return fmt.Errorf("encryption known-answer test failed")
+	}
+
+	tag := computeTag(key[cryptKeySize:], nil, ct)
+	if !bytes.Equal(tag, expected[tagStart:]) {
+		return fmt.Errorf("tag known-answer test failed")
+	}
+
+	message, err := decrypt(key[:cryptKeySize], ct)
+	if err != nil {
+		return err
+	} else if !bytes.Equal(message, selfTestMessage) {
+		return fmt.Errorf("decryption known-answer test failed")
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build cryptobox_selftest
+
+package strongbox
+
+func init() {
+	SelfTest()
+}
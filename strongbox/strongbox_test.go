@@ -2,6 +2,7 @@ package strongbox
 
 import "bytes"
 import "crypto/rand"
+import "encoding/binary"
 import "fmt"
 import "io/ioutil"
 import "math/big"
@@ -214,8 +215,63 @@ func TestEmptyBox(t *testing.T) {
 	}
 }
 
+// TestAssociatedData ensures that a box sealed with associated data
+// only opens with the same associated data.
+func TestAssociatedData(t *testing.T) {
+	var (
+		msg   = []byte(testMessages[0])
+		ad    = []byte("row 42")
+		badAD = []byte("row 43")
+	)
+
+	box, ok := SealWithAD(msg, ad, testGoodKey)
+	if !ok {
+		t.Fatal("strongbox: failed to seal message with associated data")
+	} else if len(box) != len(msg)+Overhead {
+		t.Fatal("strongbox: the box length is invalid")
+	}
+
+	out, ok := OpenWithAD(box, ad, testGoodKey)
+	if !ok {
+		t.Fatal("strongbox: failed to open message with associated data")
+	} else if !bytes.Equal(out, msg) {
+		t.Fatal("strongbox: output message doesn't match original")
+	}
+
+	if _, ok = OpenWithAD(box, badAD, testGoodKey); ok {
+		t.Fatal("strongbox: box opened with the wrong associated data")
+	} else if _, ok = OpenWithAD(box, nil, testGoodKey); ok {
+		t.Fatal("strongbox: box opened without its associated data")
+	} else if _, ok = Open(box, testGoodKey); ok {
+		t.Fatal("strongbox: box with associated data opened with Open")
+	} else if _, ok = OpenWithAD(box, ad, testBadKey); ok {
+		t.Fatal("strongbox: box opened with the wrong key")
+	}
+
+	// Moving the associated data into the box, as it is laid out
+	// under the tag, must not produce a box that opens without it.
+	forged := binary.BigEndian.AppendUint64(nil, uint64(len(ad)))
+	forged = append(forged, ad...)
+	forged = append(forged, box...)
+	if _, ok = Open(forged, testGoodKey); ok {
+		t.Fatal("strongbox: box with associated data was forged into a box without it")
+	}
+
+	// A box without associated data opens with empty associated data.
+	box, ok = Seal(msg, testGoodKey)
+	if !ok {
+		t.Fatal("strongbox: failed to seal message")
+	}
+	if _, ok = OpenWithAD(box, []byte{}, testGoodKey); !ok {
+		t.Fatal("strongbox: failed to open box with empty associated data")
+	} else if _, ok = OpenWithAD(box, ad, testGoodKey); ok {
+		t.Fatal("strongbox: box opened with unexpected associated data")
+	}
+}
+
 // Benchmark the Seal function, which secures the message.
 func BenchmarkSeal(b *testing.B) {
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		_, ok := Seal(testBoxFile, testGoodKey)
 		if !ok {
@@ -232,6 +288,8 @@ func BenchmarkOpen(b *testing.B) {
 		fmt.Println("Can't seal message: benchmark aborted.")
 		b.FailNow()
 	}
+	b.ReportAllocs()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, ok := Open(box, testGoodKey)
 		if !ok {
@@ -0,0 +1,96 @@
+package strongbox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestUsageSealer(t *testing.T) {
+	var warnings []Usage
+	opts := &UsageOptions{
+		MaxMessages:  3,
+		WarnMessages: 2,
+		OnWarn:       func(u Usage) { warnings = append(warnings, u) },
+	}
+	u, ok := NewUsageSealer(testGoodKey, opts)
+	if !ok {
+		t.Fatal("strongbox: failed to create usage sealer")
+	}
+
+	msg := []byte(testMessages[0])
+	for i := 0; i < 3; i++ {
+		box, err := u.SealE(msg)
+		if err != nil {
+			t.Fatalf("strongbox: usage sealer failed to seal message %d: %v", i, err)
+		} else if out, ok := u.Open(box); !ok || !bytes.Equal(out, msg) {
+			t.Fatalf("strongbox: failed to open box %d", i)
+		}
+	}
+
+	if _, err := u.SealE(msg); !errors.Is(err, ErrKeyWornOut) {
+		t.Fatalf("strongbox: expected ErrKeyWornOut, got %v", err)
+	} else if _, ok = u.Seal(msg); ok {
+		t.Fatal("strongbox: sealed with a worn out key")
+	} else if !u.WornOut() {
+		t.Fatal("strongbox: key should be worn out")
+	}
+
+	usage := u.Usage()
+	if usage.Messages != 3 || usage.Bytes != uint64(3*len(msg)) {
+		t.Fatalf("strongbox: invalid usage %+v", usage)
+	} else if len(warnings) != 1 || warnings[0].Messages != 2 {
+		t.Fatalf("strongbox: warning callback should be called once: %+v", warnings)
+	}
+	// The sealer keeps its own copy of the key.
+	key := append(Key{}, testGoodKey...)
+	if u, ok = NewUsageSealer(key, nil); !ok {
+		t.Fatal("strongbox: failed to create usage sealer")
+	}
+	box, ok := u.Seal(msg)
+	if !ok {
+		t.Fatal("strongbox: usage sealer failed to seal message")
+	}
+	for i := range key {
+		key[i] = 0
+	}
+	if out, ok := u.Open(box); !ok || !bytes.Equal(out, msg) {
+		t.Fatal("strongbox: usage sealer depends on the caller's key")
+	}
+}
+
+func TestUsageByteLimit(t *testing.T) {
+	u, ok := NewUsageSealer(testGoodKey, &UsageOptions{
+		MaxBytes: 100,
+		Usage:    Usage{Messages: 10, Bytes: 60},
+	})
+	if !ok {
+		t.Fatal("strongbox: failed to create usage sealer")
+	}
+
+	if _, err := u.SealE(make([]byte, 41)); !errors.Is(err, ErrKeyWornOut) {
+		t.Fatalf("strongbox: expected ErrKeyWornOut, got %v", err)
+	} else if _, err = u.SealE(make([]byte, 40)); err != nil {
+		t.Fatalf("strongbox: failed to seal message within the limit: %v", err)
+	} else if usage := u.Usage(); usage.Messages != 11 || usage.Bytes != 100 {
+		t.Fatalf("strongbox: invalid usage %+v", usage)
+	} else if !u.WornOut() {
+		t.Fatal("strongbox: key should be worn out")
+	}
+
+	// Restored usage beyond the limit must not wrap around.
+	u, ok = NewUsageSealer(testGoodKey, &UsageOptions{MaxBytes: 100, Usage: Usage{Bytes: 200}})
+	if !ok {
+		t.Fatal("strongbox: failed to create usage sealer")
+	} else if _, err := u.SealE(nil); !errors.Is(err, ErrKeyWornOut) {
+		t.Fatalf("strongbox: expected ErrKeyWornOut, got %v", err)
+	}
+
+	if u, ok = NewUsageSealer(testGoodKey, &UsageOptions{MaxMessages: DefaultMaxMessages + 1}); !ok {
+		t.Fatal("strongbox: failed to create usage sealer")
+	} else if u.maxMsgs != DefaultMaxMessages || u.maxBytes != DefaultMaxBytes {
+		t.Fatal("strongbox: limits should be capped at the defaults")
+	} else if _, ok = NewUsageSealer(testBadKey[1:], nil); ok {
+		t.Fatal("strongbox: created a usage sealer with an invalid key")
+	}
+}